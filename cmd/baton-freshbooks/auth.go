@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"golang.org/x/oauth2"
+)
+
+const (
+	redirectURL  = "redirect-url"
+	loginTimeout = "login-timeout"
+
+	defaultRedirectURL  = "http://localhost:8085/callback"
+	defaultLoginTimeout = 5 * time.Minute
+)
+
+// authorizationResult is what the redirect listener receives from FreshBooks.
+type authorizationResult struct {
+	code string
+	err  error
+}
+
+// newAuthCommand returns the `auth` command, used to get the first refresh token of a FreshBooks app.
+func newAuthCommand(ctx context.Context, v *viper.Viper) *cobra.Command {
+	authCmd := &cobra.Command{
+		Use:   "auth",
+		Short: "Manage the FreshBooks OAuth credentials",
+	}
+
+	loginCmd := &cobra.Command{
+		Use:   "login",
+		Short: "Authorize the connector with the Authorization Code grant and get a refresh token",
+		Long: "Opens a listener on the redirect URL of the FreshBooks app, prints the URL to authorize the app and exchanges\n" +
+			"the authorization code for a refresh token. The refresh token is saved in the token store when one is set,\n" +
+			"otherwise it is printed to be used with --refresh-token.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			// The flags are bound when the command runs, like the commands defined by the SDK do,
+			// so the values of the other commands sharing the same keys are not overridden.
+			err := v.BindPFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			return runAuthLogin(ctx, cmd, v)
+		},
+	}
+
+	flags := loginCmd.Flags()
+	flags.String(fbClientID, "", "Client ID from the Freshbooks app ($BATON_FB_CLIENT_ID)")
+	flags.String(fbClientSecret, "", "Client Secret from the Freshbooks app ($BATON_FB_CLIENT_SECRET)")
+	flags.String(baseURL, client.DefaultBaseURL, "Base URL of the FreshBooks API the authorization code is exchanged with ($BATON_BASE_URL)")
+	flags.String(redirectURL, defaultRedirectURL, "Redirect URI registered in the FreshBooks app, it must point to this machine ($BATON_REDIRECT_URL)")
+	flags.Duration(loginTimeout, defaultLoginTimeout, "Time to wait for the authorization to be granted ($BATON_LOGIN_TIMEOUT)")
+	flags.String(tokenStorePath, "", "Path of the file where the refresh token is stored ($BATON_TOKEN_STORE_PATH)")
+	flags.String(tokenStoreKey, "", "Passphrase used to encrypt the token store file ($BATON_TOKEN_STORE_KEY)")
+
+	authCmd.AddCommand(loginCmd)
+
+	return authCmd
+}
+
+func runAuthLogin(ctx context.Context, cmd *cobra.Command, v *viper.Viper) error {
+	argClientID := v.GetString(fbClientID)
+	argClientSecret := v.GetString(fbClientSecret)
+	if argClientID == "" || argClientSecret == "" {
+		return fmt.Errorf("[fb-client-id, fb-client-secret] arguments must be provided")
+	}
+
+	argBaseURL := v.GetString(baseURL)
+	err := validateURL(argBaseURL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", baseURL, err)
+	}
+
+	var store client.TokenStore
+	if argTokenStorePath := v.GetString(tokenStorePath); argTokenStorePath != "" {
+		fileStore, err := client.NewFileTokenStore(argTokenStorePath, v.GetString(tokenStoreKey))
+		if err != nil {
+			return err
+		}
+		store = fileStore
+	}
+
+	callbackURL, err := url.Parse(v.GetString(redirectURL))
+	if err != nil {
+		return fmt.Errorf("invalid redirect URL: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, v.GetDuration(loginTimeout))
+	defer cancel()
+
+	config := client.NewOAuthConfig(argBaseURL, argClientID, argClientSecret, callbackURL.String())
+
+	state, err := randomState()
+	if err != nil {
+		return err
+	}
+	verifier := oauth2.GenerateVerifier()
+
+	results := make(chan authorizationResult, 1)
+	server, err := startRedirectListener(callbackURL, state, results)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = server.Shutdown(context.Background())
+	}()
+
+	authURL := config.AuthCodeURL(state, oauth2.S256ChallengeOption(verifier))
+	fmt.Fprintf(cmd.ErrOrStderr(), "Open the following URL in a browser to authorize the connector:\n\n%s\n\n", authURL)
+
+	var result authorizationResult
+	select {
+	case <-ctx.Done():
+		return fmt.Errorf("timed out waiting for the authorization: %w", ctx.Err())
+	case result = <-results:
+	}
+	if result.err != nil {
+		return result.err
+	}
+
+	token, err := config.Exchange(ctx, result.code, oauth2.VerifierOption(verifier))
+	if err != nil {
+		return fmt.Errorf("error exchanging the authorization code: %w", err)
+	}
+
+	if store != nil {
+		err = store.Save(ctx, token)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(cmd.ErrOrStderr(), "The refresh token was saved in %s\n", v.GetString(tokenStorePath))
+		return nil
+	}
+
+	fmt.Fprintln(cmd.ErrOrStderr(), "Use the following value as --refresh-token, it can only be exchanged once:")
+	fmt.Fprintln(cmd.OutOrStdout(), token.RefreshToken)
+
+	return nil
+}
+
+// startRedirectListener serves the path of the redirect URL, and sends the authorization code or the error
+// returned by FreshBooks to results.
+func startRedirectListener(callbackURL *url.URL, state string, results chan<- authorizationResult) (*http.Server, error) {
+	listener, err := net.Listen("tcp", callbackURL.Host)
+	if err != nil {
+		return nil, fmt.Errorf("error listening on %s: %w", callbackURL.Host, err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc(callbackURL.Path, func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		var result authorizationResult
+		switch {
+		case query.Get("state") != state:
+			http.Error(w, "invalid state", http.StatusBadRequest)
+			return
+		case query.Get("error") != "":
+			result.err = fmt.Errorf("the authorization was denied: %s %s", query.Get("error"), query.Get("error_description"))
+		case query.Get("code") == "":
+			result.err = errors.New("the authorization code is missing from the redirect")
+		default:
+			result.code = query.Get("code")
+		}
+
+		if result.err != nil {
+			http.Error(w, result.err.Error(), http.StatusBadRequest)
+		} else {
+			fmt.Fprintln(w, "The connector was authorized, this window can be closed.")
+		}
+
+		select {
+		case results <- result:
+		default:
+		}
+	})
+
+	server := &http.Server{
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	go func() {
+		err := server.Serve(listener)
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			fmt.Fprintln(os.Stderr, err.Error())
+		}
+	}()
+
+	return server, nil
+}
+
+// randomState returns the value used to bind the authorization request to its redirect.
+func randomState() (string, error) {
+	state := make([]byte, 32)
+	_, err := rand.Read(state)
+	if err != nil {
+		return "", err
+	}
+
+	return base64.RawURLEncoding.EncodeToString(state), nil
+}
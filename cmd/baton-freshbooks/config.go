@@ -1,15 +1,38 @@
 package main
 
 import (
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+	"github.com/conductorone/baton-freshbooks/pkg/connector"
 	"github.com/conductorone/baton-sdk/pkg/field"
 	"github.com/spf13/viper"
 )
 
 const (
-	token          = "token"
-	refreshToken   = "refresh-token"
-	fbClientID     = "fb-client-id"
-	fbClientSecret = "fb-client-secret"
+	token           = "token"
+	refreshToken    = "refresh-token"
+	fbClientID      = "fb-client-id"
+	fbClientSecret  = "fb-client-secret"
+	maxRetries      = "max-retries"
+	retryBudget     = "retry-budget"
+	tokenStorePath  = "token-store-path"
+	tokenStoreKey   = "token-store-key"
+	defaultRole     = "default-role"
+	rolePermissions = "role-permissions"
+	baseURL         = "base-url"
+	httpProxy       = "http-proxy"
+	caFile          = "ca-file"
+	requestTimeout  = "request-timeout"
+	pageConcurrency = "page-concurrency"
+	teamMemberTTL   = "team-member-cache-ttl"
+	teamMemberState = "team-member-state-path"
+	teamMemberKey   = "team-member-state-key"
+	corporateDomain = "corporate-domains"
+
+	webhookQueuePath = "webhook-queue-path"
 )
 
 var (
@@ -17,11 +40,103 @@ var (
 	RefreshTokenField = field.StringField(refreshToken, field.WithRequired(false), field.WithDescription("Refresh token used to get a new access token from FreshBooks"))
 	ClientIDField     = field.StringField(fbClientID, field.WithRequired(false), field.WithDescription("Client ID from the Freshbooks app"))
 	ClientSecretField = field.StringField(fbClientSecret, field.WithRequired(false), field.WithDescription("Client Secret from the Freshbooks app"))
+	MaxRetriesField   = field.IntField(
+		maxRetries,
+		field.WithDefaultValue(5),
+		field.WithDescription("Number of times a request rejected by the FreshBooks rate limit is retried"),
+	)
+	RetryBudgetField = field.IntField(
+		retryBudget,
+		field.WithDefaultValue(120),
+		field.WithDescription("Maximum number of seconds spent waiting to retry a single rate limited request"),
+	)
+	TokenStorePathField = field.StringField(
+		tokenStorePath,
+		field.WithDescription("Path of the file where the refresh tokens rotated by FreshBooks are stored. When it holds a token, it is used instead of the refresh token argument"),
+	)
+	TokenStoreKeyField = field.StringField(
+		tokenStoreKey,
+		field.WithDescription("Passphrase used to encrypt the token store file"),
+	)
+	DefaultRoleField = field.StringField(
+		defaultRole,
+		field.WithDefaultValue(connector.DefaultRoleName),
+		field.WithDescription("Role the users are moved to when their role is revoked: business_manager, business_employee, contractor or no_seat_employee"),
+	)
+	RolePermissionsField = field.BoolField(
+		rolePermissions,
+		field.WithDescription("Add a permission entitlement to each role for what it lets its users do, from an approximate table of the FreshBooks roles"),
+	)
+	BaseURLField = field.StringField(
+		baseURL,
+		field.WithDefaultValue(client.DefaultBaseURL),
+		field.WithDescription("Base URL of the FreshBooks API, to send the requests to a stand-in like a recording proxy"),
+	)
+	HTTPProxyField = field.StringField(
+		httpProxy,
+		field.WithDescription("URL of the proxy the requests to FreshBooks go through, instead of the one from HTTPS_PROXY"),
+	)
+	CAFileField = field.StringField(
+		caFile,
+		field.WithDescription("Path of a PEM bundle of CA certificates to trust along with the system ones"),
+	)
+	RequestTimeoutField = field.IntField(
+		requestTimeout,
+		field.WithDefaultValue(300),
+		field.WithDescription("Maximum number of seconds a single request to FreshBooks can take"),
+	)
+	PageConcurrencyField = field.IntField(
+		pageConcurrency,
+		field.WithDefaultValue(client.DefaultPageConcurrency),
+		field.WithDescription("Number of pages of team members requested at the same time"),
+	)
+	TeamMemberCacheTTLField = field.IntField(
+		teamMemberTTL,
+		field.WithDefaultValue(int(connector.DefaultTeamMemberCacheTTL/time.Second)),
+		field.WithDescription("Number of seconds the team members of a business are reused by the users, roles and projects before they are requested again"),
+	)
+	TeamMemberStatePathField = field.StringField(
+		teamMemberState,
+		field.WithDescription("Path of the file where the team members are kept between syncs, so a sync only requests the ones updated since the previous one"),
+	)
+	TeamMemberStateKeyField = field.StringField(
+		teamMemberKey,
+		field.WithDescription("Passphrase used to encrypt the team member state file"),
+	)
+	CorporateDomainsField = field.StringSliceField(
+		corporateDomain,
+		field.WithDescription("Email domains of the business, the users with an email on another domain are flagged as external. Without them, only the accountants are"),
+	)
+	WebhookQueuePathField = field.StringField(
+		webhookQueuePath,
+		field.WithDescription("Path of the file where the webhook server queues the FreshBooks notifications, for the connector to refresh the team members of the businesses they are about"),
+	)
 
 	// ConfigurationFields defines the external configuration required for the
 	// connector to run. Note: these fields can be marked as optional or
 	// required.
-	ConfigurationFields = []field.SchemaField{TokenField, RefreshTokenField, ClientIDField, ClientSecretField}
+	ConfigurationFields = []field.SchemaField{
+		TokenField,
+		RefreshTokenField,
+		ClientIDField,
+		ClientSecretField,
+		MaxRetriesField,
+		RetryBudgetField,
+		TokenStorePathField,
+		TokenStoreKeyField,
+		DefaultRoleField,
+		RolePermissionsField,
+		BaseURLField,
+		HTTPProxyField,
+		CAFileField,
+		RequestTimeoutField,
+		PageConcurrencyField,
+		TeamMemberCacheTTLField,
+		TeamMemberStatePathField,
+		TeamMemberStateKeyField,
+		CorporateDomainsField,
+		WebhookQueuePathField,
+	}
 
 	// FieldRelationships defines relationships between the fields listed in
 	// ConfigurationFields that can be automatically validated. For example, a
@@ -30,6 +145,9 @@ var (
 	FieldRelationships = []field.SchemaFieldRelationship{
 		field.FieldsAtLeastOneUsed(TokenField, RefreshTokenField),
 		field.FieldsRequiredTogether(RefreshTokenField, ClientIDField, ClientSecretField),
+		field.FieldsRequiredTogether(TokenStorePathField, TokenStoreKeyField),
+		field.FieldsRequiredTogether(TeamMemberStatePathField, TeamMemberStateKeyField),
+		field.FieldsDependentOn([]field.SchemaField{TokenStorePathField}, []field.SchemaField{RefreshTokenField}),
 	}
 )
 
@@ -37,6 +155,51 @@ var (
 // error if it isn't valid. Implementing this function is optional, it only
 // needs to perform extra validations that cannot be encoded with configuration
 // parameters.
-func ValidateConfig(_ *viper.Viper) error {
+func ValidateConfig(v *viper.Viper) error {
+	if v.GetInt(maxRetries) < 0 {
+		return fmt.Errorf("%s must not be negative", maxRetries)
+	}
+
+	if v.GetInt(retryBudget) < 0 {
+		return fmt.Errorf("%s must not be negative", retryBudget)
+	}
+
+	if v.GetInt(requestTimeout) < 0 {
+		return fmt.Errorf("%s must not be negative", requestTimeout)
+	}
+
+	if v.GetInt(teamMemberTTL) < 0 {
+		return fmt.Errorf("%s must not be negative", teamMemberTTL)
+	}
+
+	if v.GetInt(pageConcurrency) < 1 {
+		return fmt.Errorf("%s must be at least 1", pageConcurrency)
+	}
+
+	for _, key := range []string{baseURL, httpProxy} {
+		err := validateURL(v.GetString(key))
+		if err != nil {
+			return fmt.Errorf("%s: %w", key, err)
+		}
+	}
+
+	return nil
+}
+
+// validateURL checks that a URL argument, when set, is an absolute http or https URL.
+func validateURL(value string) error {
+	if value == "" {
+		return nil
+	}
+
+	parsed, err := url.Parse(value)
+	if err != nil {
+		return err
+	}
+
+	if (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%q must be an absolute http or https URL", value)
+	}
+
 	return nil
 }
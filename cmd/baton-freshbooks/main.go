@@ -4,8 +4,11 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"time"
 
+	"github.com/conductorone/baton-freshbooks/pkg/client"
 	"github.com/conductorone/baton-freshbooks/pkg/connector"
+	"github.com/conductorone/baton-freshbooks/pkg/webhooks"
 	"github.com/conductorone/baton-sdk/pkg/config"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/field"
@@ -20,7 +23,7 @@ var version = "dev"
 func main() {
 	ctx := context.Background()
 
-	_, cmd, err := config.DefineConfiguration(
+	v, cmd, err := config.DefineConfiguration(
 		ctx,
 		"baton-freshbooks",
 		getConnector,
@@ -36,6 +39,9 @@ func main() {
 
 	cmd.Version = version
 
+	cmd.AddCommand(newAuthCommand(ctx, v))
+	cmd.AddCommand(newWebhooksCommand(ctx, v))
+
 	err = cmd.Execute()
 	if err != nil {
 		fmt.Fprintln(os.Stderr, err.Error())
@@ -44,6 +50,23 @@ func main() {
 }
 
 func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, error) {
+	l := ctxzap.Extract(ctx)
+
+	cb, err := newConnector(ctx, v)
+	if err != nil {
+		l.Error("error creating connector", zap.Error(err))
+		return nil, err
+	}
+	connector, err := connectorbuilder.NewConnector(ctx, cb)
+	if err != nil {
+		l.Error("error creating connector", zap.Error(err))
+		return nil, err
+	}
+	return connector, nil
+}
+
+// newConnector builds the connector from the configuration, it is shared by the sync and the webhook server.
+func newConnector(ctx context.Context, v *viper.Viper) (*connector.Connector, error) {
 	// Get arguments from Viper
 	argAccessToken := v.GetString(token)
 	argRefreshToken := v.GetString(refreshToken)
@@ -56,27 +79,63 @@ func getConnector(ctx context.Context, v *viper.Viper) (types.ConnectorServer, e
 		connectorOpts = append(connectorOpts, connector.WithAccessToken(ctx, argAccessToken))
 	} else if argRefreshToken != "" && argClientID != "" && argClientSecret != "" {
 		connectorOpts = append(connectorOpts, connector.WithRefreshToken(ctx, argRefreshToken, argClientID, argClientSecret))
+
+		if argTokenStorePath := v.GetString(tokenStorePath); argTokenStorePath != "" {
+			store, err := client.NewFileTokenStore(argTokenStorePath, v.GetString(tokenStoreKey))
+			if err != nil {
+				return nil, err
+			}
+			connectorOpts = append(connectorOpts, connector.WithTokenStore(store))
+		}
 	}
 
 	if len(connectorOpts) == 0 {
 		return nil, fmt.Errorf("[token] or [refresh-token, fb-client-id, fb-client-secret] argumetns must provided")
 	}
 
-	l := ctxzap.Extract(ctx)
+	retryConfig := client.DefaultRetryConfig()
+	retryConfig.MaxRetries = v.GetInt(maxRetries)
+	retryConfig.Budget = time.Duration(v.GetInt(retryBudget)) * time.Second
+	connectorOpts = append(connectorOpts, connector.WithRetryConfig(retryConfig))
 
-	if err := ValidateConfig(v); err != nil {
-		return nil, err
+	if argBaseURL := v.GetString(baseURL); argBaseURL != "" {
+		connectorOpts = append(connectorOpts, connector.WithBaseURL(argBaseURL))
 	}
 
-	cb, err := connector.New(ctx, connectorOpts...)
-	if err != nil {
-		l.Error("error creating connector", zap.Error(err))
-		return nil, err
+	connectorOpts = append(connectorOpts, connector.WithHTTPConfig(client.HTTPConfig{
+		ProxyURL: v.GetString(httpProxy),
+		CAFile:   v.GetString(caFile),
+		Timeout:  time.Duration(v.GetInt(requestTimeout)) * time.Second,
+	}))
+
+	connectorOpts = append(connectorOpts, connector.WithPageConcurrency(v.GetInt(pageConcurrency)))
+	connectorOpts = append(connectorOpts, connector.WithTeamMemberCacheTTL(time.Duration(v.GetInt(teamMemberTTL))*time.Second))
+
+	if argDefaultRole := v.GetString(defaultRole); argDefaultRole != "" {
+		connectorOpts = append(connectorOpts, connector.WithDefaultRole(argDefaultRole))
 	}
-	connector, err := connectorbuilder.NewConnector(ctx, cb)
-	if err != nil {
-		l.Error("error creating connector", zap.Error(err))
+
+	connectorOpts = append(connectorOpts, connector.WithRolePermissions(v.GetBool(rolePermissions)))
+
+	if argCorporateDomains := v.GetStringSlice(corporateDomain); len(argCorporateDomains) > 0 {
+		connectorOpts = append(connectorOpts, connector.WithCorporateDomains(argCorporateDomains))
+	}
+
+	if argTeamMemberStatePath := v.GetString(teamMemberState); argTeamMemberStatePath != "" {
+		connectorOpts = append(connectorOpts, connector.WithTeamMemberStatePath(argTeamMemberStatePath, v.GetString(teamMemberKey)))
+	}
+
+	if argWebhookQueuePath := v.GetString(webhookQueuePath); argWebhookQueuePath != "" {
+		queue, err := webhooks.NewQueue(argWebhookQueuePath)
+		if err != nil {
+			return nil, err
+		}
+		connectorOpts = append(connectorOpts, connector.WithWebhookQueue(queue))
+	}
+
+	if err := ValidateConfig(v); err != nil {
 		return nil, err
 	}
-	return connector, nil
+
+	return connector.New(ctx, connectorOpts...)
 }
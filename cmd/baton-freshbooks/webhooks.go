@@ -0,0 +1,179 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os/signal"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/conductorone/baton-freshbooks/pkg/webhooks"
+	"github.com/conductorone/baton-sdk/pkg/field"
+	"github.com/conductorone/baton-sdk/pkg/logging"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"go.uber.org/zap"
+)
+
+const (
+	webhookListenAddr   = "webhook-listen-addr"
+	webhookURL          = "webhook-url"
+	webhookEvents       = "webhook-events"
+	webhookVerifierPath = "webhook-verifier-path"
+	webhookVerifierKey  = "webhook-verifier-key"
+
+	defaultWebhookListenAddr = ":8086"
+)
+
+// defaultWebhookEvents are the notifications registered by default. FreshBooks has no callback for the team
+// members, the client and project ones are the closest to the changes of the users and their projects.
+var defaultWebhookEvents = []string{"client", "project"}
+
+// newWebhooksCommand returns the `webhooks` command, used to receive the FreshBooks notifications the connector
+// reads from the webhook queue.
+func newWebhooksCommand(ctx context.Context, v *viper.Viper) *cobra.Command {
+	webhooksCmd := &cobra.Command{
+		Use:   "webhooks",
+		Short: "Receive the FreshBooks webhook notifications",
+	}
+
+	serveCmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Register the webhook callbacks and queue the notifications FreshBooks posts to them",
+		Long: "Listens for the FreshBooks callbacks, registers the callbacks of every business to the webhook URL and\n" +
+			"confirms them with the verifier FreshBooks posts. The signed notifications are appended to the webhook queue,\n" +
+			"which the connector reads when it is run with the same --webhook-queue-path.",
+		RunE: func(cmd *cobra.Command, _ []string) error {
+			err := v.BindPFlags(cmd.Flags())
+			if err != nil {
+				return err
+			}
+
+			return runWebhooksServe(ctx, v)
+		},
+	}
+
+	flags := serveCmd.Flags()
+	addConfigurationFlags(flags, ConfigurationFields)
+	flags.String(webhookListenAddr, defaultWebhookListenAddr, "Address the webhook server listens on ($BATON_WEBHOOK_LISTEN_ADDR)")
+	flags.String(webhookURL, "", "Public URL FreshBooks posts the notifications to, it must reach the webhook server ($BATON_WEBHOOK_URL)")
+	flags.StringSlice(webhookEvents, defaultWebhookEvents, "Events the callbacks are registered for, either a noun like client or an event like client.update ($BATON_WEBHOOK_EVENTS)")
+	flags.String(webhookVerifierPath, "", "Path of the file where the registered callbacks and their verifiers are kept across restarts ($BATON_WEBHOOK_VERIFIER_PATH)")
+	flags.String(webhookVerifierKey, "", "Passphrase used to encrypt the webhook verifier file ($BATON_WEBHOOK_VERIFIER_KEY)")
+
+	webhooksCmd.AddCommand(serveCmd)
+
+	return webhooksCmd
+}
+
+// addConfigurationFlags adds the flags of the connector configuration, since the webhook server builds the connector
+// to register and verify the callbacks.
+func addConfigurationFlags(flags *pflag.FlagSet, fields []field.SchemaField) {
+	for _, f := range fields {
+		switch f.FieldType {
+		case reflect.Int:
+			value, _ := f.Int()
+			flags.Int(f.FieldName, value, f.GetDescription())
+		case reflect.Bool:
+			value, _ := f.Bool()
+			flags.Bool(f.FieldName, value, f.GetDescription())
+		case reflect.Slice:
+			value, _ := f.StringSlice()
+			flags.StringSlice(f.FieldName, value, f.GetDescription())
+		default:
+			value, _ := f.String()
+			flags.String(f.FieldName, value, f.GetDescription())
+		}
+	}
+}
+
+func runWebhooksServe(ctx context.Context, v *viper.Viper) error {
+	ctx, err := logging.Init(ctx, logging.WithLogFormat(v.GetString("log-format")), logging.WithLogLevel(v.GetString("log-level")))
+	if err != nil {
+		return err
+	}
+	l := ctxzap.Extract(ctx)
+
+	argWebhookURL := v.GetString(webhookURL)
+	if argWebhookURL == "" {
+		return fmt.Errorf("%s must be provided", webhookURL)
+	}
+	err = validateURL(argWebhookURL)
+	if err != nil {
+		return fmt.Errorf("%s: %w", webhookURL, err)
+	}
+
+	argQueuePath := v.GetString(webhookQueuePath)
+	if argQueuePath == "" {
+		return fmt.Errorf("%s must be provided", webhookQueuePath)
+	}
+
+	queue, err := webhooks.NewQueue(argQueuePath)
+	if err != nil {
+		return err
+	}
+
+	argVerifierPath := v.GetString(webhookVerifierPath)
+	if argVerifierPath != "" && v.GetString(webhookVerifierKey) == "" {
+		return fmt.Errorf("%s must be provided with %s", webhookVerifierKey, webhookVerifierPath)
+	}
+
+	verifiers, err := webhooks.NewVerifierStore(argVerifierPath, v.GetString(webhookVerifierKey))
+	if err != nil {
+		return err
+	}
+
+	cb, err := newConnector(ctx, v)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// The listener is opened before the callbacks are registered, since FreshBooks posts their verification right away.
+	listener, err := net.Listen("tcp", v.GetString(webhookListenAddr))
+	if err != nil {
+		return fmt.Errorf("error listening on %s: %w", v.GetString(webhookListenAddr), err)
+	}
+
+	server := &http.Server{
+		Handler:           webhooks.NewHandler(verifiers, queue, cb.VerifyWebhook),
+		ReadHeaderTimeout: 10 * time.Second,
+		BaseContext: func(net.Listener) context.Context {
+			return ctx
+		},
+	}
+
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+	l.Info("webhook server listening", zap.String("addr", listener.Addr().String()))
+
+	err = cb.RegisterWebhooks(ctx, argWebhookURL, v.GetStringSlice(webhookEvents), verifiers)
+	if err != nil {
+		_ = server.Shutdown(context.Background())
+		return err
+	}
+
+	select {
+	case err = <-serveErr:
+		if !errors.Is(err, http.ErrServerClosed) {
+			return err
+		}
+		return nil
+	case <-ctx.Done():
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return server.Shutdown(shutdownCtx)
+}
@@ -3,38 +3,79 @@ package client
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/uhttp"
 	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
 	"golang.org/x/oauth2"
 )
 
 const (
-	baseURL       = "https://api.freshbooks.com/auth"
+	authorizeURL = "https://auth.freshbooks.com/oauth/authorize"
+
+	// DefaultBaseURL is the host of the FreshBooks APIs.
+	DefaultBaseURL = "https://api.freshbooks.com"
+
+	authPath      = "/auth"
 	getNewToken   = "/oauth/token" // #nosec G101
 	getBusinessID = "/api/v1/users/me"
 
-	businessBaseURL = "/api/v1/businesses/"
+	businessBaseURL = "/api/v1/businesses"
 	getTeamMembers  = "/team_members"
+
+	projectsBaseURL = "/projects/business"
+	getProjects     = "/projects"
+	getProject      = "/project"
+
+	accountingBaseURL = "/accounting/account"
+	getClients        = "/users/clients"
+	getStaffs         = "/users/staffs"
+
+	eventsBaseURL = "/events/account"
+	getCallbacks  = "/events/callbacks"
+)
+
+var (
+	// ErrNoBusinessMemberships is returned when the identity behind the token isn't a member of any business.
+	ErrNoBusinessMemberships = errors.New("the identity is not a member of any FreshBooks business")
+	// ErrNoAccountingAccount is returned when a business has no accounting account, so the accounting API can't be used.
+	ErrNoAccountingAccount = errors.New("the business has no accounting account")
 )
 
 type FreshBooksClient struct {
 	client      *uhttp.BaseHttpClient
 	TokenSource oauth2.TokenSource
 	Config      Config
+	retryConfig RetryConfig
+	baseURL     string
+	httpConfig  HTTPConfig
+	httpClient  *http.Client
+
+	pageConcurrency int
+
+	oauthConfig  *oauth2.Config
+	refreshToken string
+	tokenStore   TokenStore
 }
 
+// Config holds the state resolved from the API that is shared between requests.
+// Each business is indexed by its ID, along with the accounting account ID used by the accounting API and the role
+// of the identity in it.
 type Config struct {
-	businessID      string
-	businessIDMutex sync.Mutex
+	businesses      []Business
+	businessesByID  map[string]Business
+	businessRoles   map[string]string
+	businessesMutex sync.Mutex
 }
 
 type Option func(client *FreshBooksClient)
@@ -47,48 +88,158 @@ func WithBearerToken(apiToken string) Option {
 
 // WithRefreshToken it receives a Refresh Token, Client ID and Client Secret from the platform to be able to renew the token when expired.
 // The 3 arguments should be received when the connector is executed.
-func WithRefreshToken(ctx context.Context, refreshToken, clientID, clientSecret string) Option {
+func WithRefreshToken(_ context.Context, refreshToken, clientID, clientSecret string) Option {
 	return func(client *FreshBooksClient) {
-		token := &oauth2.Token{
-			AccessToken:  "",
-			RefreshToken: refreshToken,
-			Expiry:       time.Now().Add(-1 * time.Second),
+		client.refreshToken = refreshToken
+		client.oauthConfig = NewOAuthConfig("", clientID, clientSecret, "")
+	}
+}
+
+// NewOAuthConfig returns the OAuth 2.0 configuration of a FreshBooks app, used to exchange
+// authorization codes and refresh tokens for access tokens. The tokens are requested to baseURL,
+// or to DefaultBaseURL when it is empty.
+func NewOAuthConfig(baseURL, clientID, clientSecret, redirectURL string) *oauth2.Config {
+	if baseURL == "" {
+		baseURL = DefaultBaseURL
+	}
+
+	return &oauth2.Config{
+		ClientID:     clientID,
+		ClientSecret: clientSecret,
+		RedirectURL:  redirectURL,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:  authorizeURL,
+			TokenURL: strings.TrimSuffix(baseURL, "/") + authPath + getNewToken,
+		},
+	}
+}
+
+// WithBaseURL sends the requests to another host instead of the FreshBooks API, like a fake server in the tests.
+// The access tokens are requested to it too.
+func WithBaseURL(baseURL string) Option {
+	return func(client *FreshBooksClient) {
+		client.baseURL = strings.TrimSuffix(baseURL, "/")
+	}
+}
+
+// WithTokenStore persists the tokens obtained with the refresh token, and starts from the stored token when there is one.
+// It only has effect along with WithRefreshToken.
+func WithTokenStore(store TokenStore) Option {
+	return func(client *FreshBooksClient) {
+		client.tokenStore = store
+	}
+}
+
+// setupRefreshTokenSource builds the TokenSource that renews the access token when expired.
+// A token found in the token store takes precedence over the configured refresh token, since
+// FreshBooks invalidates the configured one the first time it is exchanged.
+func (f *FreshBooksClient) setupRefreshTokenSource(ctx context.Context) error {
+	token := &oauth2.Token{
+		AccessToken:  "",
+		RefreshToken: f.refreshToken,
+		Expiry:       time.Now().Add(-1 * time.Second),
+	}
+
+	if f.tokenStore != nil {
+		storedToken, err := f.tokenStore.Load(ctx)
+		if err != nil {
+			return fmt.Errorf("error loading token from the token store: %w", err)
 		}
 
-		config := oauth2.Config{
-			ClientID:     clientID,
-			ClientSecret: clientSecret,
-			Endpoint: oauth2.Endpoint{
-				TokenURL: baseURL + getNewToken,
-			},
+		if storedToken != nil && storedToken.RefreshToken != "" {
+			token = storedToken
 		}
-		tokenSource := oauth2.ReuseTokenSource(token, config.TokenSource(ctx, token))
+	}
+
+	// The tokens are requested with the same proxy, CA certificates and timeout as the rest of the requests.
+	ctx = context.WithValue(ctx, oauth2.HTTPClient, f.httpClient)
 
-		client.TokenSource = tokenSource
+	tokenSource := oauth2.ReuseTokenSource(token, f.oauthConfig.TokenSource(ctx, token))
+	if f.tokenStore != nil {
+		tokenSource = &persistingTokenSource{
+			ctx:          ctx,
+			source:       tokenSource,
+			store:        f.tokenStore,
+			refreshToken: token.RefreshToken,
+		}
 	}
+	f.TokenSource = tokenSource
+
+	return nil
 }
 
-func (f *FreshBooksClient) EnsureBusinessID(ctx context.Context) error {
-	f.Config.businessIDMutex.Lock()
-	defer f.Config.businessIDMutex.Unlock()
+// EnsureBusinesses requests the businesses the identity is a member of, only once.
+func (f *FreshBooksClient) EnsureBusinesses(ctx context.Context) error {
+	f.Config.businessesMutex.Lock()
+	defer f.Config.businessesMutex.Unlock()
 
-	if f.BusinessID() == "" {
-		businessID, err := f.RequestBusinessID(ctx)
+	if len(f.Config.businesses) == 0 {
+		memberships, err := f.RequestBusinessMemberships(ctx)
 		if err != nil {
 			return err
 		}
-		f.SetBusinessID(businessID)
+
+		f.Config.businesses = make([]Business, 0, len(memberships))
+		f.Config.businessesByID = make(map[string]Business, len(memberships))
+		f.Config.businessRoles = make(map[string]string, len(memberships))
+		for _, membership := range memberships {
+			businessID := strconv.FormatInt(membership.Business.ID, 10)
+			f.Config.businesses = append(f.Config.businesses, membership.Business)
+			f.Config.businessesByID[businessID] = membership.Business
+			f.Config.businessRoles[businessID] = membership.Role
+		}
 	}
 
 	return nil
 }
 
-func (f *FreshBooksClient) BusinessID() string {
-	return f.Config.businessID
+// Business returns the business with the given ID, resolving the businesses first if needed.
+func (f *FreshBooksClient) Business(ctx context.Context, businessID string) (Business, error) {
+	err := f.EnsureBusinesses(ctx)
+	if err != nil {
+		return Business{}, err
+	}
+
+	f.Config.businessesMutex.Lock()
+	defer f.Config.businessesMutex.Unlock()
+
+	business, ok := f.Config.businessesByID[businessID]
+	if !ok {
+		return Business{}, fmt.Errorf("business %s not found", businessID)
+	}
+
+	return business, nil
+}
+
+// AccountID returns the accounting account ID of a business, resolving the businesses first if needed.
+func (f *FreshBooksClient) AccountID(ctx context.Context, businessID string) (string, error) {
+	business, err := f.Business(ctx, businessID)
+	if err != nil {
+		return "", err
+	}
+
+	if business.AccountID == "" {
+		return "", fmt.Errorf("business %s: %w", businessID, ErrNoAccountingAccount)
+	}
+
+	return business.AccountID, nil
+}
+
+// BusinessRole returns the role of the identity in a business resolved by EnsureBusinesses, or an empty string when
+// FreshBooks didn't tell it.
+func (f *FreshBooksClient) BusinessRole(businessID string) string {
+	f.Config.businessesMutex.Lock()
+	defer f.Config.businessesMutex.Unlock()
+
+	return f.Config.businessRoles[businessID]
 }
 
-func (f *FreshBooksClient) SetBusinessID(bid int64) {
-	f.Config.businessID = strconv.FormatInt(bid, 10)
+// Businesses returns the businesses resolved by EnsureBusinesses.
+func (f *FreshBooksClient) Businesses() []Business {
+	f.Config.businessesMutex.Lock()
+	defer f.Config.businessesMutex.Unlock()
+
+	return f.Config.businesses
 }
 
 func (f *FreshBooksClient) Token() (*oauth2.Token, error) {
@@ -100,22 +251,37 @@ func (f *FreshBooksClient) SetToken(token string) {
 }
 
 func New(ctx context.Context, opts ...Option) (*FreshBooksClient, error) {
-	httpClient, err := uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)))
+	fbClient := FreshBooksClient{
+		retryConfig:     DefaultRetryConfig(),
+		baseURL:         DefaultBaseURL,
+		pageConcurrency: DefaultPageConcurrency,
+	}
+
+	for _, o := range opts {
+		o(&fbClient)
+	}
+
+	httpClient, err := newHTTPClient(ctx, fbClient.httpConfig)
 	if err != nil {
 		return nil, err
 	}
+	fbClient.httpClient = httpClient
 
-	cli, err := uhttp.NewBaseHttpClientWithContext(context.Background(), httpClient)
+	fbClient.client, err = uhttp.NewBaseHttpClientWithContext(context.Background(), httpClient)
 	if err != nil {
 		return nil, err
 	}
 
-	fbClient := FreshBooksClient{
-		client: cli,
-	}
+	if fbClient.oauthConfig != nil {
+		fbClient.oauthConfig.Endpoint.TokenURL, err = fbClient.authURL(getNewToken)
+		if err != nil {
+			return nil, err
+		}
 
-	for _, o := range opts {
-		o(&fbClient)
+		err = fbClient.setupRefreshTokenSource(ctx)
+		if err != nil {
+			return nil, err
+		}
 	}
 
 	return &fbClient, nil
@@ -137,45 +303,318 @@ func (f *FreshBooksClient) getListFromAPI(
 	return annotation, nil
 }
 
-// ListTeamMembers Gets all the Team Members from FreshBooks and deserialized them into an Array.
-func (f *FreshBooksClient) ListTeamMembers(ctx context.Context, opts PageOptions) ([]TeamMember, string, annotations.Annotations, error) {
-	queryUrl, err := url.JoinPath(baseURL, businessBaseURL, f.BusinessID(), getTeamMembers)
+// ListTeamMembers Gets all the Team Members of a business from FreshBooks and deserialized them into an Array.
+// The request options narrow the list, like WithUpdatedSince.
+func (f *FreshBooksClient) ListTeamMembers(
+	ctx context.Context,
+	businessID string,
+	opts PageOptions,
+	reqOpts ...ReqOpt,
+) ([]TeamMember, string, annotations.Annotations, error) {
+	queryUrl, err := f.businessURL(businessID, getTeamMembers)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
 	var res Response
+	reqOpts = append([]ReqOpt{WithPage(opts.Page), WithPageLimit(opts.PerPage)}, reqOpts...)
+	annotation, err := f.getListFromAPI(ctx, queryUrl, &res, reqOpts...)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return res.Response, nextPage(res.Metadata), annotation, nil
+}
+
+// ListAllTeamMembers Gets every Team Member of a business, requesting the pages after the first one concurrently.
+// The request options narrow the list, like WithUpdatedSince.
+func (f *FreshBooksClient) ListAllTeamMembers(ctx context.Context, businessID string, reqOpts ...ReqOpt) ([]TeamMember, annotations.Annotations, error) {
+	queryUrl, err := f.businessURL(businessID, getTeamMembers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fetchAllPages(ctx, f.pageConcurrency, func(ctx context.Context, page int) ([]TeamMember, Meta, annotations.Annotations, error) {
+		var res Response
+		annotation, err := f.getListFromAPI(ctx, queryUrl, &res, append([]ReqOpt{WithPage(page), WithPageLimit(ItemsPerPage)}, reqOpts...)...)
+		if err != nil {
+			return nil, Meta{}, annotation, err
+		}
+
+		return res.Response, res.Metadata, annotation, nil
+	})
+}
+
+// GetTeamMember Gets a single Team Member of a business.
+func (f *FreshBooksClient) GetTeamMember(ctx context.Context, businessID, teamMemberUUID string) (*TeamMember, annotations.Annotations, error) {
+	queryUrl, err := f.businessURL(businessID, getTeamMembers, teamMemberUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res TeamMemberResponse
+	annotation, err := f.doRequest(ctx, http.MethodGet, queryUrl, &res, nil)
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	return &res.Response, annotation, nil
+}
+
+// InviteTeamMember Invites a person to join a business, returning the Team Member pending to accept the invitation.
+func (f *FreshBooksClient) InviteTeamMember(ctx context.Context, businessID string, invitation TeamMemberInvitation) (*TeamMember, annotations.Annotations, error) {
+	queryUrl, err := f.businessURL(businessID, getTeamMembers)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res TeamMemberResponse
+	annotation, err := f.doRequest(ctx, http.MethodPost, queryUrl, &res, invitation)
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	return &res.Response, annotation, nil
+}
+
+// UpdateTeamMember Changes the role or the status of a Team Member of a business, returning the updated Team Member.
+func (f *FreshBooksClient) UpdateTeamMember(
+	ctx context.Context,
+	businessID string,
+	teamMemberUUID string,
+	update TeamMemberUpdate,
+) (*TeamMember, annotations.Annotations, error) {
+	queryUrl, err := f.businessURL(businessID, getTeamMembers, teamMemberUUID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res TeamMemberResponse
+	annotation, err := f.doRequest(ctx, http.MethodPut, queryUrl, &res, update)
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	return &res.Response, annotation, nil
+}
+
+// ListProjects Gets the Projects of a business, along with the members of their teams.
+// The request options narrow the list, like WithUpdatedSince.
+func (f *FreshBooksClient) ListProjects(
+	ctx context.Context,
+	businessID string,
+	opts PageOptions,
+	reqOpts ...ReqOpt,
+) ([]Project, string, annotations.Annotations, error) {
+	queryUrl, err := f.projectsURL(businessID, getProjects)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var res ProjectsResponse
+	reqOpts = append([]ReqOpt{WithPage(opts.Page), WithPageLimit(opts.PerPage)}, reqOpts...)
+	annotation, err := f.getListFromAPI(ctx, queryUrl, &res, reqOpts...)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return res.Projects, nextPage(res.Metadata), annotation, nil
+}
+
+// GetProject Gets a single Project of a business, along with the members of its team.
+func (f *FreshBooksClient) GetProject(ctx context.Context, businessID, projectID string) (*Project, annotations.Annotations, error) {
+	queryUrl, err := f.projectsURL(businessID, getProject, projectID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res ProjectResponse
+	annotation, err := f.doRequest(ctx, http.MethodGet, queryUrl, &res, nil)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &res.Project, annotation, nil
+}
+
+// ListClients Gets the Clients of an accounting account, along with their contacts.
+func (f *FreshBooksClient) ListClients(ctx context.Context, accountID string, opts PageOptions) ([]Client, string, annotations.Annotations, error) {
+	queryUrl, err := f.accountingURL(accountID, getClients)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var res AccountingResponse[ClientsResult]
+	annotation, err := f.getListFromAPI(ctx, queryUrl, &res, WithPage(opts.Page), WithPageLimit(opts.PerPage), WithIncludeContacts())
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return res.Response.Result.Clients, nextPage(res.Response.Result.Meta), annotation, nil
+}
+
+// ListAllClients Gets every Client of an accounting account, along with their contacts, requesting all the pages.
+func (f *FreshBooksClient) ListAllClients(ctx context.Context, accountID string) ([]Client, annotations.Annotations, error) {
+	queryUrl, err := f.accountingURL(accountID, getClients)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fetchAllPages(ctx, f.pageConcurrency, func(ctx context.Context, page int) ([]Client, Meta, annotations.Annotations, error) {
+		var res AccountingResponse[ClientsResult]
+		annotation, err := f.getListFromAPI(ctx, queryUrl, &res, WithPage(page), WithPageLimit(ItemsPerPage), WithIncludeContacts())
+		if err != nil {
+			return nil, Meta{}, annotation, err
+		}
+
+		return res.Response.Result.Clients, res.Response.Result.Meta, annotation, nil
+	})
+}
+
+// GetClient Gets a single Client of an accounting account, along with its contacts.
+func (f *FreshBooksClient) GetClient(ctx context.Context, accountID, clientID string) (*Client, annotations.Annotations, error) {
+	queryUrl, err := f.accountingURL(accountID, getClients, clientID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res AccountingResponse[ClientResult]
+	annotation, err := f.doRequest(ctx, http.MethodGet, queryUrl, &res, nil, WithIncludeContacts())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return &res.Response.Result.Client, annotation, nil
+}
+
+// ListStaff Gets the Staff of a legacy accounting account.
+func (f *FreshBooksClient) ListStaff(ctx context.Context, accountID string, opts PageOptions) ([]Staff, string, annotations.Annotations, error) {
+	queryUrl, err := f.accountingURL(accountID, getStaffs)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var res AccountingResponse[StaffsResult]
 	annotation, err := f.getListFromAPI(ctx, queryUrl, &res, WithPage(opts.Page), WithPageLimit(opts.PerPage))
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	var nextPage string
-	paginationData := res.Metadata
-	if paginationData.Page*paginationData.PerPage < paginationData.Total {
-		nextPage = strconv.Itoa(paginationData.Page + 1)
+	return res.Response.Result.Staffs, nextPage(res.Response.Result.Meta), annotation, nil
+}
+
+// ListCallbacks Gets every webhook callback registered on an accounting account, requesting all the pages.
+func (f *FreshBooksClient) ListCallbacks(ctx context.Context, accountID string) ([]Callback, annotations.Annotations, error) {
+	queryUrl, err := f.eventsURL(accountID, getCallbacks)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return fetchAllPages(ctx, f.pageConcurrency, func(ctx context.Context, page int) ([]Callback, Meta, annotations.Annotations, error) {
+		var res AccountingResponse[CallbacksResult]
+		annotation, err := f.getListFromAPI(ctx, queryUrl, &res, WithPage(page), WithPageLimit(ItemsPerPage))
+		if err != nil {
+			return nil, Meta{}, annotation, err
+		}
+
+		return res.Response.Result.Callbacks, res.Response.Result.Meta, annotation, nil
+	})
+}
+
+// CreateCallback Registers a webhook callback for an event of an accounting account. FreshBooks then sends a
+// verification request to the URI, which must be answered with VerifyCallback before any event is sent.
+func (f *FreshBooksClient) CreateCallback(ctx context.Context, accountID, event, uri string) (*Callback, annotations.Annotations, error) {
+	queryUrl, err := f.eventsURL(accountID, getCallbacks)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res AccountingResponse[CallbackResult]
+	annotation, err := f.doRequest(ctx, http.MethodPost, queryUrl, &res, CallbackRequest{
+		Callback: CallbackFields{Event: event, URI: uri},
+	})
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	return &res.Response.Result.Callback, annotation, nil
+}
+
+// VerifyCallback Confirms a webhook callback with the verifier FreshBooks sent to its URI.
+func (f *FreshBooksClient) VerifyCallback(ctx context.Context, accountID, callbackID, verifier string) (*Callback, annotations.Annotations, error) {
+	queryUrl, err := f.eventsURL(accountID, getCallbacks, callbackID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var res AccountingResponse[CallbackResult]
+	annotation, err := f.doRequest(ctx, http.MethodPut, queryUrl, &res, CallbackRequest{
+		Callback: CallbackFields{Verifier: verifier},
+	})
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	return &res.Response.Result.Callback, annotation, nil
+}
+
+// ResendCallbackVerification Asks FreshBooks to send the verification of a webhook callback to its URI again.
+func (f *FreshBooksClient) ResendCallbackVerification(ctx context.Context, accountID, callbackID string) (annotations.Annotations, error) {
+	queryUrl, err := f.eventsURL(accountID, getCallbacks, callbackID)
+	if err != nil {
+		return nil, err
+	}
+
+	var res AccountingResponse[CallbackResult]
+	annotation, err := f.doRequest(ctx, http.MethodPut, queryUrl, &res, CallbackRequest{
+		Callback: CallbackFields{Resend: true},
+	})
+	if err != nil {
+		return annotation, err
 	}
 
-	return res.Response, nextPage, annotation, nil
+	return annotation, nil
 }
 
-func (f *FreshBooksClient) RequestBusinessID(ctx context.Context) (int64, error) {
+// RequestBusinesses gets every business the identity behind the token is a member of.
+func (f *FreshBooksClient) RequestBusinesses(ctx context.Context) ([]Business, error) {
+	memberships, err := f.RequestBusinessMemberships(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	businesses := make([]Business, 0, len(memberships))
+	for _, membership := range memberships {
+		businesses = append(businesses, membership.Business)
+	}
+
+	return businesses, nil
+}
+
+// RequestBusinessMemberships gets the membership of the identity behind the token in each of its businesses,
+// along with its role there.
+func (f *FreshBooksClient) RequestBusinessMemberships(ctx context.Context) ([]BusinessMembership, error) {
 	var response ResponseBID
-	queryUrl, err := url.JoinPath(baseURL, getBusinessID)
+	queryUrl, err := f.authURL(getBusinessID)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	_, err = f.doRequest(ctx, http.MethodGet, queryUrl, &response, nil)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
 	if len(response.Response.BusinessMemberships) == 0 {
-		return 0, fmt.Errorf("business ID not found")
+		return nil, ErrNoBusinessMemberships
 	}
 
-	return response.Response.BusinessMemberships[0].Business.ID, nil
+	return response.Response.BusinessMemberships, nil
+}
+
+// ClearHTTPCache drops the cached responses of the GET requests, so the next requests reach the API.
+func (f *FreshBooksClient) ClearHTTPCache(ctx context.Context) error {
+	return uhttp.ClearCaches(ctx)
 }
 
 func (f *FreshBooksClient) doRequest(
@@ -183,13 +622,10 @@ func (f *FreshBooksClient) doRequest(
 	method string,
 	endpointUrl string,
 	res interface{},
-	_ interface{},
+	body interface{},
 	reqOpts ...ReqOpt,
 ) (annotations.Annotations, error) {
-	var (
-		resp *http.Response
-		err  error
-	)
+	var resp *http.Response
 
 	urlAddress, err := url.Parse(endpointUrl)
 	if err != nil {
@@ -202,41 +638,100 @@ func (f *FreshBooksClient) doRequest(
 
 	clientToken, err := f.Token()
 	if err != nil {
-		return nil, err
+		return nil, newTokenError(err)
 	}
 
-	req, err := f.client.NewRequest(
-		ctx,
-		method,
-		urlAddress,
+	l := ctxzap.Extract(ctx)
+
+	requestOptions := []uhttp.RequestOption{
 		uhttp.WithAcceptJSONHeader(),
 		uhttp.WithContentTypeJSONHeader(),
 		uhttp.WithHeader("Authorization", "Bearer "+clientToken.AccessToken),
-	)
-	if err != nil {
-		return nil, err
+	}
+	if body != nil {
+		requestOptions = append(requestOptions, uhttp.WithJSONBody(body))
 	}
 
-	resp, err = f.client.Do(req)
-	if err != nil {
-		return nil, err
+	var (
+		retries int
+		waited  time.Duration
+	)
+	for {
+		var req *http.Request
+		req, err = f.client.NewRequest(ctx, method, urlAddress, requestOptions...)
+		if err != nil {
+			return nil, err
+		}
+
+		// err is kept after the loop, so the transport errors, which come without a response, are returned.
+		resp, err = f.client.Do(req)
+		if !shouldRetry(method, resp) || retries >= f.retryConfig.MaxRetries {
+			break
+		}
+
+		delay := f.retryConfig.retryDelay(resp, retries)
+		if waited+delay > f.retryConfig.Budget {
+			break
+		}
+
+		l.Debug(
+			"freshbooks request rejected, retrying",
+			zap.String("url", urlAddress.String()),
+			zap.Int("status_code", resp.StatusCode),
+			zap.Int("retry", retries+1),
+			zap.Duration("delay", delay),
+		)
+		_ = resp.Body.Close()
+
+		if err := waitForRetry(ctx, delay); err != nil {
+			return nil, err
+		}
+		waited += delay
+		retries++
 	}
 	if resp != nil {
 		defer resp.Body.Close()
 	}
 
-	if res != nil {
-		bodyContent, err := io.ReadAll(resp.Body)
+	annotation := annotations.Annotations{}
+	rateLimit := extractRateLimitData(resp)
+	if rateLimit != nil {
+		annotation.WithRateLimiting(rateLimit)
+	}
+
+	// uhttp already turns a non 2xx status code into an error, but without the
+	// details FreshBooks sends in the body, so the error envelope is parsed here.
+	if resp != nil && (resp.StatusCode < http.StatusOK || resp.StatusCode >= http.StatusMultipleChoices) {
+		apiErr := newAPIError(resp)
+		apiErr.RateLimit = rateLimit
+		return annotation, apiErr
+	}
+	if err != nil {
+		return annotation, err
+	}
 
+	// The responses of the GET requests are cached, so they are dropped once something is changed
+	// for the next requests to see the change.
+	if method != http.MethodGet {
+		err = uhttp.ClearCaches(ctx)
 		if err != nil {
-			return nil, err
+			l.Warn("error clearing the http cache", zap.Error(err))
 		}
-		err = json.Unmarshal(bodyContent, &res)
+	}
+
+	if res != nil {
+		bodyContent, err := io.ReadAll(resp.Body)
 		if err != nil {
-			return nil, err
+			return annotation, err
+		}
+
+		if len(bodyContent) > 0 {
+			err = json.Unmarshal(bodyContent, &res)
+			if err != nil {
+				return annotation, fmt.Errorf("error decoding response from %s: %w", urlAddress.Path, err)
+			}
 		}
 	}
 
-	annotation := annotations.Annotations{}
 	return annotation, nil
 }
@@ -0,0 +1,47 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDoRequestSendsJSONBodyOnEveryAttempt(t *testing.T) {
+	var updates []TeamMemberUpdate
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodPut, r.Method)
+
+		var update TeamMemberUpdate
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&update))
+		updates = append(updates, update)
+
+		if len(updates) == 1 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"response": {"uuid": "abc", "business_role_name": "contractor"}}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, RetryConfig{MaxRetries: 1, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Budget: time.Second})
+
+	var res TeamMemberResponse
+	_, err := c.doRequest(context.Background(), http.MethodPut, server.URL+"/team_members/abc", &res, TeamMemberUpdate{BusinessRoleName: "contractor"})
+	require.NoError(t, err)
+
+	require.Len(t, updates, 2)
+	for _, update := range updates {
+		assert.Equal(t, "contractor", update.BusinessRoleName)
+		assert.Nil(t, update.Active)
+	}
+	assert.Equal(t, "contractor", res.Response.BusinessRoleName)
+}
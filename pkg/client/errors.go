@@ -0,0 +1,163 @@
+package client
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// APIError is returned when FreshBooks answers a request with a non 2xx status code.
+// It implements GRPCStatus so baton can tell a bad credential from a transient outage.
+type APIError struct {
+	StatusCode int
+	Errors     []ErrorDetail
+	// RateLimit holds the rate limit state sent along the error, if any.
+	RateLimit *v2.RateLimitDescription
+}
+
+// ErrorDetail is a single entry of the errors returned by FreshBooks.
+type ErrorDetail struct {
+	Message string `json:"message,omitempty"`
+	Errno   int    `json:"errno,omitempty"`
+	Field   string `json:"field,omitempty"`
+	Object  string `json:"object,omitempty"`
+}
+
+// errorResponse covers the different envelopes used by the FreshBooks APIs to return errors:
+// the top level `errors` of the auth API, the `response.errors` of the accounting API
+// and the `error`/`error_description` pair of the OAuth endpoints.
+type errorResponse struct {
+	Errors   []ErrorDetail `json:"errors,omitempty"`
+	Response struct {
+		Errors []ErrorDetail `json:"errors,omitempty"`
+	} `json:"response,omitempty"`
+	Error            string `json:"error,omitempty"`
+	ErrorDescription string `json:"error_description,omitempty"`
+	Message          string `json:"message,omitempty"`
+}
+
+func (e *APIError) Error() string {
+	messages := make([]string, 0, len(e.Errors))
+	for _, detail := range e.Errors {
+		message := detail.Message
+		if detail.Field != "" {
+			message = detail.Field + ": " + message
+		}
+		if detail.Errno != 0 {
+			message = fmt.Sprintf("%s (errno %d)", message, detail.Errno)
+		}
+		messages = append(messages, message)
+	}
+
+	if len(messages) == 0 {
+		return fmt.Sprintf("freshbooks: request failed with status %d: %s", e.StatusCode, http.StatusText(e.StatusCode))
+	}
+
+	return fmt.Sprintf("freshbooks: request failed with status %d: %s", e.StatusCode, strings.Join(messages, "; "))
+}
+
+// GRPCStatus maps the HTTP status code of the error into a gRPC status.
+// The rate limit state is attached as a detail, so the syncer knows when to try again.
+func (e *APIError) GRPCStatus() *status.Status {
+	st := status.New(grpcCode(e.StatusCode), e.Error())
+	if e.RateLimit != nil {
+		if withDetails, err := st.WithDetails(e.RateLimit); err == nil {
+			return withDetails
+		}
+	}
+
+	return st
+}
+
+func grpcCode(statusCode int) codes.Code {
+	switch statusCode {
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusRequestTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusTooManyRequests:
+		return codes.Unavailable
+	}
+
+	if statusCode >= http.StatusInternalServerError {
+		return codes.Unavailable
+	}
+
+	return codes.Unknown
+}
+
+// newTokenError turns a failed token exchange into an APIError, so an expired or revoked
+// refresh token is reported as Unauthenticated instead of an opaque OAuth error.
+func newTokenError(err error) error {
+	var retrieveErr *oauth2.RetrieveError
+	if !errors.As(err, &retrieveErr) || retrieveErr.Response == nil {
+		return err
+	}
+
+	statusCode := retrieveErr.Response.StatusCode
+	if statusCode == http.StatusBadRequest {
+		// FreshBooks answers an invalid or revoked grant with a 400.
+		statusCode = http.StatusUnauthorized
+	}
+
+	apiErr := &APIError{StatusCode: statusCode}
+	message := retrieveErr.ErrorCode
+	if retrieveErr.ErrorDescription != "" {
+		message = strings.TrimPrefix(message+": "+retrieveErr.ErrorDescription, ": ")
+	}
+	if message != "" {
+		apiErr.Errors = append(apiErr.Errors, ErrorDetail{Message: message})
+	}
+
+	return apiErr
+}
+
+// newAPIError builds an APIError from a failed response, parsing the error envelope when the body has one.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{
+		StatusCode: resp.StatusCode,
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || len(body) == 0 {
+		return apiErr
+	}
+
+	var errResp errorResponse
+	if err := json.Unmarshal(body, &errResp); err != nil {
+		return apiErr
+	}
+
+	apiErr.Errors = append(apiErr.Errors, errResp.Errors...)
+	apiErr.Errors = append(apiErr.Errors, errResp.Response.Errors...)
+
+	if errResp.Error != "" || errResp.ErrorDescription != "" {
+		message := errResp.Error
+		if errResp.ErrorDescription != "" {
+			message = strings.TrimPrefix(message+": "+errResp.ErrorDescription, ": ")
+		}
+		apiErr.Errors = append(apiErr.Errors, ErrorDetail{Message: message})
+	}
+
+	if len(apiErr.Errors) == 0 && errResp.Message != "" {
+		apiErr.Errors = append(apiErr.Errors, ErrorDetail{Message: errResp.Message})
+	}
+
+	return apiErr
+}
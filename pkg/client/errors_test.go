@@ -0,0 +1,67 @@
+package client
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestNewAPIError(t *testing.T) {
+	tests := []struct {
+		name       string
+		statusCode int
+		body       string
+		code       codes.Code
+		message    string
+	}{
+		{
+			name:       "auth api errors",
+			statusCode: http.StatusForbidden,
+			body:       `{"errors": [{"message": "Insufficient permissions", "errno": 1003}]}`,
+			code:       codes.PermissionDenied,
+			message:    "Insufficient permissions (errno 1003)",
+		},
+		{
+			name:       "accounting api errors",
+			statusCode: http.StatusNotFound,
+			body:       `{"response": {"errors": [{"errno": 1012, "field": "userid", "message": "Client not found.", "object": "client"}]}}`,
+			code:       codes.NotFound,
+			message:    "userid: Client not found. (errno 1012)",
+		},
+		{
+			name:       "oauth errors",
+			statusCode: http.StatusUnauthorized,
+			body:       `{"error": "unauthenticated", "error_description": "This action requires authentication to continue."}`,
+			code:       codes.Unauthenticated,
+			message:    "unauthenticated: This action requires authentication to continue.",
+		},
+		{
+			name:       "server error without body",
+			statusCode: http.StatusBadGateway,
+			code:       codes.Unavailable,
+			message:    "Bad Gateway",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := &http.Response{
+				StatusCode: tt.statusCode,
+				Body:       io.NopCloser(strings.NewReader(tt.body)),
+			}
+
+			err := newAPIError(resp)
+			assert.Equal(t, tt.statusCode, err.StatusCode)
+			assert.Contains(t, err.Error(), tt.message)
+
+			st, ok := status.FromError(err)
+			assert.True(t, ok)
+			assert.Equal(t, tt.code, st.Code())
+		})
+	}
+}
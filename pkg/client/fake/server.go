@@ -0,0 +1,793 @@
+// Package fake serves an in-process imitation of the FreshBooks APIs used by the connector, so the client and the
+// resource builders can be tested without live credentials.
+//
+// It covers the identity (users/me), the team members of each business, with the same pagination metadata and
+// updated_since filter FreshBooks has, the OAuth token endpoint, which rotates the refresh token on every exchange
+// like FreshBooks does, the staff and the clients of the legacy accounting accounts, and error responses queued
+// with FailNext.
+//
+// It also stands in for the webhook callbacks: registering a callback, or asking for its verification to be resent,
+// posts its verification to the callback URI, and Notify posts a notification signed with the verifier to the
+// verified callbacks of the event.
+package fake
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+	"github.com/conductorone/baton-freshbooks/pkg/webhooks"
+)
+
+const (
+	// AccessToken is accepted by the server from the start, to use with client.WithBearerToken.
+	AccessToken = "fake-access-token"
+	// RefreshToken is the first refresh token the server exchanges, to use with client.WithRefreshToken.
+	RefreshToken = "fake-refresh-token"
+	// ClientID and ClientSecret are the credentials of the app the refresh tokens are issued to.
+	ClientID     = "fake-client-id"
+	ClientSecret = "fake-client-secret"
+
+	// IdentityID is the identity behind the tokens issued by the server.
+	IdentityID = 1001
+
+	defaultPerPage = 15
+	maxPerPage     = 100
+	tokenLifetime  = 43200
+)
+
+// Server is a fake FreshBooks API. Its URL is meant to be passed to client.WithBaseURL.
+type Server struct {
+	*httptest.Server
+
+	mu            sync.Mutex
+	businesses    []client.Business
+	businessRoles map[string]string
+	teamMembers   map[string][]client.TeamMember
+	staffs        map[string][]client.Staff
+	clients       map[string][]client.Client
+	accessTokens  map[string]bool
+	refreshToken  string
+	tokenRequests int
+	failures      []failure
+	requests      []string
+	queries       []string
+	callbacks     map[string][]callback
+	callbackCount int
+	// ignoreUpdatedSince makes the team member list answer every team member, whatever its updated_since filter.
+	ignoreUpdatedSince bool
+}
+
+// callback is a webhook registered on an accounting account, along with the verifier posted to its URI.
+type callback struct {
+	client.Callback
+	verifier string
+}
+
+// failure is an error response queued to answer the next request.
+type failure struct {
+	statusCode int
+	message    string
+}
+
+// NewServer starts a fake FreshBooks API without businesses. It must be closed once the test is done.
+func NewServer() *Server {
+	s := &Server{
+		businessRoles: make(map[string]string),
+		teamMembers:   make(map[string][]client.TeamMember),
+		staffs:        make(map[string][]client.Staff),
+		clients:       make(map[string][]client.Client),
+		callbacks:     make(map[string][]callback),
+		accessTokens:  map[string]bool{AccessToken: true},
+		refreshToken:  RefreshToken,
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /auth/oauth/token", s.handleToken)
+	mux.HandleFunc("GET /auth/api/v1/users/me", s.authenticated(s.handleIdentity))
+	mux.HandleFunc("GET /auth/api/v1/businesses/{businessID}/team_members", s.authenticated(s.handleListTeamMembers))
+	mux.HandleFunc("POST /auth/api/v1/businesses/{businessID}/team_members", s.authenticated(s.handleInviteTeamMember))
+	mux.HandleFunc("GET /auth/api/v1/businesses/{businessID}/team_members/{uuid}", s.authenticated(s.handleGetTeamMember))
+	mux.HandleFunc("PUT /auth/api/v1/businesses/{businessID}/team_members/{uuid}", s.authenticated(s.handleUpdateTeamMember))
+	mux.HandleFunc("GET /accounting/account/{accountID}/users/staffs", s.authenticated(s.handleListStaff))
+	mux.HandleFunc("GET /accounting/account/{accountID}/users/clients", s.authenticated(s.handleListClients))
+	mux.HandleFunc("GET /events/account/{accountID}/events/callbacks", s.authenticated(s.handleListCallbacks))
+	mux.HandleFunc("POST /events/account/{accountID}/events/callbacks", s.authenticated(s.handleCreateCallback))
+	mux.HandleFunc("PUT /events/account/{accountID}/events/callbacks/{callbackID}", s.authenticated(s.handleVerifyCallback))
+
+	s.Server = httptest.NewServer(s.recordAndFail(mux))
+
+	return s
+}
+
+// AddBusiness makes the identity a member of the business, as its owner.
+func (s *Server) AddBusiness(business client.Business) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.businesses = append(s.businesses, business)
+	s.businessRoles[strconv.FormatInt(business.ID, 10)] = "owner"
+}
+
+// IgnoreUpdatedSince makes the server list every team member even when the request has an updated_since filter.
+func (s *Server) IgnoreUpdatedSince() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ignoreUpdatedSince = true
+}
+
+// SetBusinessRole changes the role of the identity in a business.
+func (s *Server) SetBusinessRole(businessID int64, role string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.businessRoles[strconv.FormatInt(businessID, 10)] = role
+}
+
+// AddTeamMembers adds team members to a business, its ID is set on them.
+func (s *Server) AddTeamMembers(businessID int64, teamMembers ...client.TeamMember) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := strconv.FormatInt(businessID, 10)
+	for _, teamMember := range teamMembers {
+		teamMember.BusinessID = int(businessID)
+		s.teamMembers[key] = append(s.teamMembers[key], teamMember)
+	}
+}
+
+// AddStaff adds legacy staff to an accounting account.
+func (s *Server) AddStaff(accountID string, staffs ...client.Staff) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.staffs[accountID] = append(s.staffs[accountID], staffs...)
+}
+
+// AddClients adds clients, along with their contacts, to an accounting account.
+func (s *Server) AddClients(accountID string, clients ...client.Client) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.clients[accountID] = append(s.clients[accountID], clients...)
+}
+
+// TeamMember returns the current state of a team member, to check the changes made through the API.
+func (s *Server) TeamMember(businessID int64, uuid string) (client.TeamMember, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.teamMemberIndex(strconv.FormatInt(businessID, 10), uuid)
+	if i < 0 {
+		return client.TeamMember{}, false
+	}
+
+	return s.teamMembers[strconv.FormatInt(businessID, 10)][i], true
+}
+
+// FailNext answers the next request with the given status code, whatever the endpoint is.
+// The rate limit responses (429) tell the client to retry right away.
+func (s *Server) FailNext(statusCode int, message string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.failures = append(s.failures, failure{statusCode: statusCode, message: message})
+}
+
+// RevokeAccessTokens invalidates every access token issued so far, so the next requests are rejected until the
+// client gets a new one.
+func (s *Server) RevokeAccessTokens() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.accessTokens = make(map[string]bool)
+}
+
+// CurrentRefreshToken returns the only refresh token the server accepts, since each exchange rotates it.
+func (s *Server) CurrentRefreshToken() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.refreshToken
+}
+
+// TokenRequests returns how many times the OAuth token endpoint was called.
+func (s *Server) TokenRequests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.tokenRequests
+}
+
+// Requests returns the method and path of every request received, in order, like "GET /auth/api/v1/users/me".
+func (s *Server) Requests() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.requests)
+}
+
+// Queries returns the query string of every request received, in the same order as Requests.
+func (s *Server) Queries() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.queries)
+}
+
+// Callbacks returns the webhook callbacks registered on an accounting account.
+func (s *Server) Callbacks(accountID string) []client.Callback {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var ret []client.Callback
+	for _, cb := range s.callbacks[accountID] {
+		ret = append(ret, cb.Callback)
+	}
+
+	return ret
+}
+
+// Notify posts a notification of a change in a business to the verified callbacks of the event, signed with their
+// verifier like FreshBooks does. It returns the status code of each post.
+func (s *Server) Notify(businessID int64, name, objectID string) ([]int, error) {
+	s.mu.Lock()
+	accountID := ""
+	for _, business := range s.businesses {
+		if business.ID == businessID {
+			accountID = business.AccountID
+		}
+	}
+	var targets []callback
+	for _, cb := range s.callbacks[accountID] {
+		if cb.Verified && (cb.Event == name || strings.HasPrefix(name, cb.Event+".")) {
+			targets = append(targets, cb)
+		}
+	}
+	s.mu.Unlock()
+
+	form := url.Values{
+		"name":        {name},
+		"object_id":   {objectID},
+		"account_id":  {accountID},
+		"business_id": {strconv.FormatInt(businessID, 10)},
+		"identity_id": {strconv.Itoa(IdentityID)},
+	}
+
+	var codes []int
+	for _, cb := range targets {
+		code, err := postForm(cb.URI, form, cb.verifier)
+		if err != nil {
+			return codes, err
+		}
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+func (s *Server) recordAndFail(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		s.requests = append(s.requests, r.Method+" "+r.URL.Path)
+		s.queries = append(s.queries, r.URL.RawQuery)
+		var pending *failure
+		if len(s.failures) > 0 {
+			pending = &s.failures[0]
+			s.failures = s.failures[1:]
+		}
+		s.mu.Unlock()
+
+		if pending != nil {
+			if pending.statusCode == http.StatusTooManyRequests {
+				w.Header().Set("Retry-After", "0")
+			}
+			writeErrors(w, pending.statusCode, pending.message)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// authenticated rejects the requests without a valid access token, like FreshBooks does.
+func (s *Server) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		accessToken, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+
+		s.mu.Lock()
+		valid := ok && s.accessTokens[accessToken]
+		s.mu.Unlock()
+
+		if !valid {
+			writeJSON(w, http.StatusUnauthorized, map[string]string{
+				"error":             "unauthenticated",
+				"error_description": "This action requires authentication to continue.",
+			})
+			return
+		}
+
+		next(w, r)
+	}
+}
+
+// handleToken exchanges the current refresh token for a new access token and a new refresh token.
+func (s *Server) handleToken(w http.ResponseWriter, r *http.Request) {
+	err := r.ParseForm()
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request", "error_description": err.Error()})
+		return
+	}
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.tokenRequests++
+
+	switch {
+	case clientID != ClientID || clientSecret != ClientSecret:
+		writeJSON(w, http.StatusUnauthorized, map[string]string{
+			"error":             "invalid_client",
+			"error_description": "Client authentication failed due to unknown client, no client authentication included, or unsupported authentication method.",
+		})
+		return
+	case r.PostForm.Get("grant_type") != "refresh_token" || r.PostForm.Get("refresh_token") != s.refreshToken:
+		writeJSON(w, http.StatusBadRequest, map[string]string{
+			"error":             "invalid_grant",
+			"error_description": "The provided authorization grant is invalid, expired, revoked, does not match the redirection URI used in the authorization request, or was issued to another client.",
+		})
+		return
+	}
+
+	accessToken := fmt.Sprintf("fake-access-token-%d", s.tokenRequests)
+	s.accessTokens[accessToken] = true
+	s.refreshToken = fmt.Sprintf("fake-refresh-token-%d", s.tokenRequests)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  accessToken,
+		"token_type":    "Bearer",
+		"expires_in":    tokenLifetime,
+		"refresh_token": s.refreshToken,
+		"scope":         "user:profile:read",
+	})
+}
+
+func (s *Server) handleIdentity(w http.ResponseWriter, _ *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	memberships := make([]client.BusinessMembership, 0, len(s.businesses))
+	for i, business := range s.businesses {
+		memberships = append(memberships, client.BusinessMembership{
+			ID:       int64(i + 1),
+			Role:     s.businessRoles[strconv.FormatInt(business.ID, 10)],
+			Business: business,
+		})
+	}
+
+	writeJSON(w, http.StatusOK, client.ResponseBID{
+		Response: client.UserResponse{
+			ID:                  IdentityID,
+			IdentityID:          IdentityID,
+			IdentityUUID:        "fake-identity-uuid",
+			BusinessMemberships: memberships,
+		},
+	})
+}
+
+func (s *Server) handleListTeamMembers(w http.ResponseWriter, r *http.Request) {
+	page, perPage, err := pageParams(r)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	updatedSince, err := updatedSinceParam(r)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	businessID := r.PathValue("businessID")
+	if !s.hasBusiness(businessID) {
+		writeErrors(w, http.StatusForbidden, "The identity is not a member of the business")
+		return
+	}
+
+	teamMembers := s.teamMembers[businessID]
+	if !updatedSince.IsZero() && !s.ignoreUpdatedSince {
+		teamMembers = slices.DeleteFunc(slices.Clone(teamMembers), func(teamMember client.TeamMember) bool {
+			return !updatedAtOrAfter(teamMember, updatedSince)
+		})
+	}
+	total := len(teamMembers)
+	start := min((page-1)*perPage, total)
+	end := min(start+perPage, total)
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"response": append([]client.TeamMember{}, teamMembers[start:end]...),
+		"meta": client.Meta{
+			Page:    page,
+			PerPage: perPage,
+			Pages:   (total + perPage - 1) / perPage,
+			Total:   total,
+		},
+	})
+}
+
+func (s *Server) handleGetTeamMember(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	businessID := r.PathValue("businessID")
+	i := s.teamMemberIndex(businessID, r.PathValue("uuid"))
+	if i < 0 {
+		writeErrors(w, http.StatusNotFound, "Team member not found")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, client.TeamMemberResponse{Response: s.teamMembers[businessID][i]})
+}
+
+func (s *Server) handleUpdateTeamMember(w http.ResponseWriter, r *http.Request) {
+	var update client.TeamMemberUpdate
+	err := json.NewDecoder(r.Body).Decode(&update)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	businessID := r.PathValue("businessID")
+	i := s.teamMemberIndex(businessID, r.PathValue("uuid"))
+	if i < 0 {
+		writeErrors(w, http.StatusNotFound, "Team member not found")
+		return
+	}
+
+	teamMember := &s.teamMembers[businessID][i]
+	if update.BusinessRoleName != "" {
+		teamMember.BusinessRoleName = update.BusinessRoleName
+	}
+	if update.Active != nil {
+		teamMember.Active = *update.Active
+	}
+	teamMember.UpdatedAt = timestamp()
+
+	writeJSON(w, http.StatusOK, client.TeamMemberResponse{Response: *teamMember})
+}
+
+func (s *Server) handleInviteTeamMember(w http.ResponseWriter, r *http.Request) {
+	var invitation client.TeamMemberInvitation
+	err := json.NewDecoder(r.Body).Decode(&invitation)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	businessID := r.PathValue("businessID")
+	if !s.hasBusiness(businessID) {
+		writeErrors(w, http.StatusForbidden, "The identity is not a member of the business")
+		return
+	}
+
+	for _, teamMember := range s.teamMembers[businessID] {
+		if strings.EqualFold(teamMember.Email, invitation.Email) {
+			writeErrors(w, http.StatusConflict, "A team member with this email already exists")
+			return
+		}
+	}
+
+	id, _ := strconv.Atoi(businessID)
+	teamMember := client.TeamMember{
+		UUID:             fmt.Sprintf("fake-team-member-%d", len(s.teamMembers[businessID])+1),
+		FirstName:        invitation.FirstName,
+		LastName:         invitation.LastName,
+		Email:            invitation.Email,
+		BusinessID:       id,
+		BusinessRoleName: invitation.BusinessRoleName,
+		Active:           true,
+		Invited:          true,
+		CreatedAt:        timestamp(),
+		UpdatedAt:        timestamp(),
+	}
+	s.teamMembers[businessID] = append(s.teamMembers[businessID], teamMember)
+
+	writeJSON(w, http.StatusOK, client.TeamMemberResponse{Response: teamMember})
+}
+
+func (s *Server) handleListStaff(w http.ResponseWriter, r *http.Request) {
+	page, perPage, err := pageParams(r)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	staffs := s.staffs[r.PathValue("accountID")]
+	total := len(staffs)
+	start := min((page-1)*perPage, total)
+	end := min(start+perPage, total)
+
+	writeAccountingResult(w, client.StaffsResult{
+		Staffs: append([]client.Staff{}, staffs[start:end]...),
+		Meta: client.Meta{
+			Page:    page,
+			PerPage: perPage,
+			Pages:   (total + perPage - 1) / perPage,
+			Total:   total,
+		},
+	})
+}
+
+func (s *Server) handleListClients(w http.ResponseWriter, r *http.Request) {
+	page, perPage, err := pageParams(r)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	clients := s.clients[r.PathValue("accountID")]
+	total := len(clients)
+	start := min((page-1)*perPage, total)
+	end := min(start+perPage, total)
+
+	writeAccountingResult(w, client.ClientsResult{
+		Clients: append([]client.Client{}, clients[start:end]...),
+		Meta: client.Meta{
+			Page:    page,
+			PerPage: perPage,
+			Pages:   (total + perPage - 1) / perPage,
+			Total:   total,
+		},
+	})
+}
+
+func (s *Server) handleListCallbacks(w http.ResponseWriter, r *http.Request) {
+	page, perPage, err := pageParams(r)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	callbacks := s.Callbacks(r.PathValue("accountID"))
+	total := len(callbacks)
+	start := min((page-1)*perPage, total)
+	end := min(start+perPage, total)
+
+	writeAccountingResult(w, client.CallbacksResult{
+		Callbacks: callbacks[start:end],
+		Meta: client.Meta{
+			Page:    page,
+			PerPage: perPage,
+			Pages:   (total + perPage - 1) / perPage,
+			Total:   total,
+		},
+	})
+}
+
+// handleCreateCallback registers a callback, and posts its verification to the callback URI once the response is
+// sent, since FreshBooks waits for the callback to be registered before verifying it.
+func (s *Server) handleCreateCallback(w http.ResponseWriter, r *http.Request) {
+	var request client.CallbackRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil || request.Callback.Event == "" || request.Callback.URI == "" {
+		writeErrors(w, http.StatusBadRequest, "A callback needs an event and a uri")
+		return
+	}
+
+	accountID := r.PathValue("accountID")
+
+	s.mu.Lock()
+	s.callbackCount++
+	cb := callback{
+		Callback: client.Callback{
+			CallbackID: int64(s.callbackCount),
+			Event:      request.Callback.Event,
+			URI:        request.Callback.URI,
+		},
+		verifier: fmt.Sprintf("fake-verifier-%d", s.callbackCount),
+	}
+	s.callbacks[accountID] = append(s.callbacks[accountID], cb)
+	s.mu.Unlock()
+
+	writeCallback(w, cb.Callback)
+
+	go postVerification(accountID, cb)
+}
+
+// postVerification posts the verifier of a callback to its URI, unsigned like FreshBooks does.
+func postVerification(accountID string, cb callback) {
+	_, _ = postForm(cb.URI, url.Values{
+		"name":       {"callback.verify"},
+		"object_id":  {strconv.FormatInt(cb.CallbackID, 10)},
+		"account_id": {accountID},
+		"verifier":   {cb.verifier},
+	}, "")
+}
+
+func (s *Server) handleVerifyCallback(w http.ResponseWriter, r *http.Request) {
+	var request client.CallbackRequest
+	err := json.NewDecoder(r.Body).Decode(&request)
+	if err != nil {
+		writeErrors(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	accountID := r.PathValue("accountID")
+	i := slices.IndexFunc(s.callbacks[accountID], func(cb callback) bool {
+		return strconv.FormatInt(cb.CallbackID, 10) == r.PathValue("callbackID")
+	})
+	if i < 0 {
+		writeErrors(w, http.StatusNotFound, "Callback not found")
+		return
+	}
+
+	cb := &s.callbacks[accountID][i]
+	if request.Callback.Resend {
+		writeCallback(w, cb.Callback)
+		go postVerification(accountID, *cb)
+		return
+	}
+
+	if request.Callback.Verifier != cb.verifier {
+		writeErrors(w, http.StatusUnprocessableEntity, "The verifier is not valid")
+		return
+	}
+	cb.Verified = true
+
+	writeCallback(w, cb.Callback)
+}
+
+func writeCallback(w http.ResponseWriter, cb client.Callback) {
+	writeAccountingResult(w, client.CallbackResult{Callback: cb})
+}
+
+// writeAccountingResult answers with the envelope of the accounting and events APIs.
+func writeAccountingResult(w http.ResponseWriter, result interface{}) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"response": map[string]interface{}{"result": result},
+	})
+}
+
+// postForm posts a form to a callback URI, signed with the verifier when it is set.
+func postForm(uri string, form url.Values, verifier string) (int, error) {
+	body := form.Encode()
+
+	req, err := http.NewRequest(http.MethodPost, uri, strings.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if verifier != "" {
+		req.Header.Set(webhooks.SignatureHeader, webhooks.Sign(verifier, []byte(body)))
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+// timestamp returns the current time the way FreshBooks formats the timestamps of the team members.
+func timestamp() string {
+	return time.Now().UTC().Format(time.RFC3339)
+}
+
+func (s *Server) hasBusiness(businessID string) bool {
+	for _, business := range s.businesses {
+		if strconv.FormatInt(business.ID, 10) == businessID {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (s *Server) teamMemberIndex(businessID, uuid string) int {
+	return slices.IndexFunc(s.teamMembers[businessID], func(teamMember client.TeamMember) bool {
+		return teamMember.UUID == uuid
+	})
+}
+
+// pageParams reads the pagination parameters the same way FreshBooks does: the first page by default,
+// 15 items per page by default and never more than 100.
+func pageParams(r *http.Request) (int, int, error) {
+	page, perPage := 1, defaultPerPage
+
+	query := r.URL.Query()
+	if value := query.Get("page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, 0, fmt.Errorf("invalid page %q", value)
+		}
+		page = parsed
+	}
+
+	if value := query.Get("per_page"); value != "" {
+		parsed, err := strconv.Atoi(value)
+		if err != nil || parsed < 1 {
+			return 0, 0, fmt.Errorf("invalid per_page %q", value)
+		}
+		perPage = min(parsed, maxPerPage)
+	}
+
+	return page, perPage, nil
+}
+
+// updatedSinceParam returns the time of the updated_since filter, or a zero time when there is none.
+func updatedSinceParam(r *http.Request) (time.Time, error) {
+	value := r.URL.Query().Get("updated_since")
+	if value == "" {
+		return time.Time{}, nil
+	}
+
+	since, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid updated_since %q", value)
+	}
+
+	return since, nil
+}
+
+// updatedAtOrAfter reports whether a team member was updated, or created when it never was, at or after since.
+// The team members without either date are left out, like the objects FreshBooks has no date for.
+func updatedAtOrAfter(teamMember client.TeamMember, since time.Time) bool {
+	value := teamMember.UpdatedAt
+	if value == "" {
+		value = teamMember.CreatedAt
+	}
+
+	updatedAt, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return false
+	}
+
+	return !updatedAt.Before(since)
+}
+
+// writeErrors writes the error envelope of the auth API.
+func writeErrors(w http.ResponseWriter, statusCode int, message string) {
+	writeJSON(w, statusCode, map[string]interface{}{
+		"errors": []client.ErrorDetail{{Message: message}},
+	})
+}
+
+func writeJSON(w http.ResponseWriter, statusCode int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+	_ = json.NewEncoder(w).Encode(body)
+}
@@ -8,6 +8,7 @@ type Response struct {
 type Meta struct {
 	Page    int `json:"page,omitempty"`
 	PerPage int `json:"per_page,omitempty"`
+	Pages   int `json:"pages,omitempty"`
 	Total   int `json:"total,omitempty"`
 }
 
@@ -17,7 +18,7 @@ type TeamMember struct {
 	MiddleName             string `json:"middle_name,omitempty"`
 	LastName               string `json:"last_name,omitempty"`
 	Email                  string `json:"email,omitempty"`
-	JobTitle               string `json:"job_tittle,omitempty"`
+	JobTitle               string `json:"job_title,omitempty"`
 	Street1                string `json:"street_1,omitempty"`
 	Street2                string `json:"street_2,omitempty"`
 	City                   string `json:"city,omitempty"`
@@ -29,13 +30,32 @@ type TeamMember struct {
 	BusinessID             int    `json:"business_id,omitempty"`
 	BusinessRoleName       string `json:"business_role_name,omitempty"`
 	Active                 bool   `json:"active,omitempty"`
-	IdentityId             int    `json:"identity_it,omitempty"`
+	IdentityID             int64  `json:"identity_id,omitempty"`
 	InvitationDateAccepted string `json:"invitation_date_accepted,omitempty"`
 	CreatedAt              string `json:"created_at,omitempty"`
 	UpdatedAt              string `json:"updated_at,omitempty"`
 	Invited                bool   `json:"invited,omitempty"`
 }
 
+// TeamMemberResponse is the envelope of a single Team Member.
+type TeamMemberResponse struct {
+	Response TeamMember `json:"response"`
+}
+
+// TeamMemberUpdate holds the fields of a Team Member that can be changed, only the ones set are sent.
+type TeamMemberUpdate struct {
+	BusinessRoleName string `json:"business_role_name,omitempty"`
+	Active           *bool  `json:"active,omitempty"`
+}
+
+// TeamMemberInvitation holds the details of a person invited to join a business as a Team Member.
+type TeamMemberInvitation struct {
+	Email            string `json:"email"`
+	FirstName        string `json:"first_name,omitempty"`
+	LastName         string `json:"last_name,omitempty"`
+	BusinessRoleName string `json:"business_role_name"`
+}
+
 type Role struct {
 	RoleName         string
 	BusinessRoleName string
@@ -52,8 +72,11 @@ type UserResponse struct {
 	BusinessMemberships []BusinessMembership `json:"business_memberships"`
 }
 
+// BusinessMembership is the membership of the identity in a business. Role is the role of the identity there,
+// like owner or admin.
 type BusinessMembership struct {
 	ID       int64    `json:"id"`
+	Role     string   `json:"role,omitempty"`
 	Business Business `json:"business"`
 }
 
@@ -61,4 +84,132 @@ type Business struct {
 	ID           int64  `json:"id"`
 	BusinessUUID string `json:"business_uuid"`
 	Name         string `json:"name"`
+	AccountID    string `json:"account_id"`
+}
+
+type ProjectsResponse struct {
+	Projects []Project `json:"projects,omitempty"`
+	Metadata Meta      `json:"meta,omitempty"`
+}
+
+type ProjectResponse struct {
+	Project Project `json:"project"`
+}
+
+type Project struct {
+	ID          int64        `json:"id"`
+	Title       string       `json:"title,omitempty"`
+	Description string       `json:"description,omitempty"`
+	Active      bool         `json:"active,omitempty"`
+	Complete    bool         `json:"complete,omitempty"`
+	ClientID    int64        `json:"client_id,omitempty"`
+	Group       ProjectGroup `json:"group,omitempty"`
+}
+
+type ProjectGroup struct {
+	ID      int64           `json:"id"`
+	Members []ProjectMember `json:"members,omitempty"`
+}
+
+// ProjectMember is a member of the team of a project. Its role is either "owner" or "member".
+type ProjectMember struct {
+	ID         int64  `json:"id"`
+	IdentityID int64  `json:"identity_id,omitempty"`
+	Role       string `json:"role,omitempty"`
+	FirstName  string `json:"first_name,omitempty"`
+	LastName   string `json:"last_name,omitempty"`
+	Email      string `json:"email,omitempty"`
+	Company    string `json:"company,omitempty"`
+	Active     bool   `json:"active,omitempty"`
+}
+
+// AccountingResponse is the envelope used by the accounting API.
+type AccountingResponse[T any] struct {
+	Response struct {
+		Result T `json:"result"`
+	} `json:"response"`
+}
+
+type ClientsResult struct {
+	Clients []Client `json:"clients,omitempty"`
+	Meta
+}
+
+type ClientResult struct {
+	Client Client `json:"client"`
+}
+
+type StaffsResult struct {
+	Staffs []Staff `json:"staffs,omitempty"`
+	Meta
+}
+
+type CallbacksResult struct {
+	Callbacks []Callback `json:"callbacks,omitempty"`
+	Meta
+}
+
+type CallbackResult struct {
+	Callback Callback `json:"callback"`
+}
+
+// Callback is a webhook registered on an accounting account: FreshBooks posts the events matching Event to URI
+// once it is verified. Event is either an event name like client.create, or a noun like client for all its events.
+type Callback struct {
+	CallbackID int64  `json:"callbackid"`
+	Event      string `json:"event"`
+	URI        string `json:"uri"`
+	Verified   bool   `json:"verified"`
+}
+
+// CallbackRequest is the body used to register or verify a webhook callback, or to have its verification resent.
+type CallbackRequest struct {
+	Callback CallbackFields `json:"callback"`
+}
+
+type CallbackFields struct {
+	Event    string `json:"event,omitempty"`
+	URI      string `json:"uri,omitempty"`
+	Verifier string `json:"verifier,omitempty"`
+	Resend   bool   `json:"resend,omitempty"`
+}
+
+// Client is a customer of the business. Its email, along with the emails of its contacts, can log into the client portal.
+type Client struct {
+	ID           int64           `json:"id"`
+	UserID       int64           `json:"userid,omitempty"`
+	FirstName    string          `json:"fname,omitempty"`
+	LastName     string          `json:"lname,omitempty"`
+	Email        string          `json:"email,omitempty"`
+	Organization string          `json:"organization,omitempty"`
+	Username     string          `json:"username,omitempty"`
+	VisState     int             `json:"vis_state"`
+	SignupDate   string          `json:"signup_date,omitempty"`
+	Updated      string          `json:"updated,omitempty"`
+	Contacts     []ClientContact `json:"contacts,omitempty"`
+}
+
+// Staff is a user of a legacy FreshBooks account, listed by the accounting API. The businesses of the new platform
+// list their users as team members, but the staff of the older accounts are only listed here.
+type Staff struct {
+	ID          int64  `json:"id"`
+	IdentityID  int64  `json:"identity_id,omitempty"`
+	FirstName   string `json:"fname,omitempty"`
+	LastName    string `json:"lname,omitempty"`
+	Email       string `json:"email,omitempty"`
+	Username    string `json:"username,omitempty"`
+	DisplayName string `json:"display_name,omitempty"`
+	Level       int    `json:"level"`
+	VisState    int    `json:"vis_state"`
+	LastLogin   string `json:"last_login,omitempty"`
+	SignupDate  string `json:"signup_date,omitempty"`
+	Updated     string `json:"updated,omitempty"`
+}
+
+type ClientContact struct {
+	ID        int64  `json:"id"`
+	UserID    int64  `json:"userid,omitempty"`
+	FirstName string `json:"fname,omitempty"`
+	LastName  string `json:"lname,omitempty"`
+	Email     string `json:"email,omitempty"`
 }
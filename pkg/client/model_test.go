@@ -0,0 +1,293 @@
+package client
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// strictDecode makes the decode tests fail when a fixture has fields the models don't know about. The fixtures are
+// written by hand after the responses documented by FreshBooks, so it catches the documented fields a model misses,
+// not the changes of the live API.
+// Run with `go test ./pkg/client -run TestDecode -args -strict-decode` or FRESHBOOKS_STRICT_DECODE=1.
+var strictDecode = flag.Bool("strict-decode", os.Getenv("FRESHBOOKS_STRICT_DECODE") != "", "fail on fixture fields the models don't decode")
+
+func TestDecodeFixtures(t *testing.T) {
+	tests := []struct {
+		fixture  string
+		expected interface{}
+	}{
+		{
+			fixture: "team_members.json",
+			expected: Response{
+				Response: []TeamMember{
+					{
+						UUID:                   "6b9f3c1e-2d4a-4f7b-9c1d-8e2f0a7b5c31",
+						FirstName:              "Jane",
+						MiddleName:             "A",
+						LastName:               "Doe",
+						Email:                  "jane.doe@example.com",
+						JobTitle:               "Bookkeeper",
+						Street1:                "100 King St W",
+						Street2:                "Suite 200",
+						City:                   "Toronto",
+						Province:               "Ontario",
+						Country:                "Canada",
+						PostalCode:             "M5X 1A9",
+						CountryCode:            "CA",
+						PhoneNumber:            "416-555-0100",
+						BusinessID:             4521187,
+						BusinessRoleName:       "business_manager",
+						Active:                 true,
+						IdentityID:             8823411,
+						InvitationDateAccepted: "2023-11-21T09:15:00Z",
+						CreatedAt:              "2023-11-20T08:30:00Z",
+						UpdatedAt:              "2024-01-02T00:00:00Z",
+						Invited:                true,
+					},
+					{
+						UUID:             "0c7e61aa-93b5-4c2e-a1f4-5d9b8e3c2f10",
+						FirstName:        "Sam",
+						LastName:         "Lee",
+						Email:            "sam.lee@example.com",
+						BusinessID:       4521187,
+						BusinessRoleName: "contractor",
+						Active:           true,
+						CreatedAt:        "2024-02-10T14:00:00Z",
+						UpdatedAt:        "2024-02-10T14:00:00Z",
+						Invited:          true,
+					},
+				},
+				Metadata: Meta{Page: 1, PerPage: 15, Total: 2},
+			},
+		},
+		{
+			fixture: "team_member.json",
+			expected: TeamMemberResponse{
+				Response: TeamMember{
+					UUID:                   "0c7e61aa-93b5-4c2e-a1f4-5d9b8e3c2f10",
+					FirstName:              "Sam",
+					LastName:               "Lee",
+					Email:                  "sam.lee@example.com",
+					BusinessID:             4521187,
+					BusinessRoleName:       "business_employee",
+					IdentityID:             9014472,
+					InvitationDateAccepted: "2024-02-11T16:20:00Z",
+					CreatedAt:              "2024-02-10T14:00:00Z",
+					UpdatedAt:              "2024-05-03T12:45:00Z",
+					Invited:                true,
+				},
+			},
+		},
+		{
+			fixture: "users_me.json",
+			expected: ResponseBID{
+				Response: UserResponse{
+					ID:           8823411,
+					IdentityID:   8823411,
+					IdentityUUID: "f1a2b3c4-d5e6-4789-8abc-def012345678",
+					BusinessMemberships: []BusinessMembership{
+						{
+							ID:   7712003,
+							Role: "owner",
+							Business: Business{
+								ID:           4521187,
+								BusinessUUID: "2e4c6a8b-1d3f-4a5b-9c7d-0e1f2a3b4c5d",
+								Name:         "Acme Consulting",
+								AccountID:    "xZNQ1X",
+							},
+						},
+						{
+							ID:   7712950,
+							Role: "admin",
+							Business: Business{
+								ID:           4530021,
+								BusinessUUID: "9a8b7c6d-5e4f-4321-8fed-cba987654321",
+								Name:         "Acme Labs",
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			fixture: "projects.json",
+			expected: ProjectsResponse{
+				Projects: []Project{
+					{
+						ID:          1290411,
+						Title:       "Website redesign",
+						Description: "New marketing site",
+						Active:      true,
+						ClientID:    301,
+						Group: ProjectGroup{
+							ID: 5520981,
+							Members: []ProjectMember{
+								{
+									ID:         7790112,
+									IdentityID: 8823411,
+									Role:       "owner",
+									FirstName:  "Jane",
+									LastName:   "Doe",
+									Email:      "jane.doe@example.com",
+									Company:    "Acme Consulting",
+									Active:     true,
+								},
+								{
+									ID:         7790113,
+									IdentityID: 9014472,
+									Role:       "member",
+									FirstName:  "Sam",
+									LastName:   "Lee",
+									Email:      "sam.lee@example.com",
+									Company:    "Acme Consulting",
+									Active:     true,
+								},
+							},
+						},
+					},
+				},
+				Metadata: Meta{Page: 1, PerPage: 15, Total: 1},
+			},
+		},
+		{
+			fixture: "project.json",
+			expected: ProjectResponse{
+				Project: Project{
+					ID:       1290455,
+					Title:    "Annual audit",
+					Complete: true,
+					Group: ProjectGroup{
+						ID: 5520999,
+						Members: []ProjectMember{
+							{
+								ID:         7790120,
+								IdentityID: 8823411,
+								Role:       "owner",
+								FirstName:  "Jane",
+								LastName:   "Doe",
+								Email:      "jane.doe@example.com",
+								Company:    "Acme Consulting",
+								Active:     true,
+							},
+						},
+					},
+				},
+			},
+		},
+		{
+			fixture: "clients.json",
+			expected: accountingResponse(ClientsResult{
+				Clients: []Client{
+					testClient(),
+					{
+						ID:         302,
+						UserID:     302,
+						Email:      "billing@initech.example.com",
+						VisState:   1,
+						SignupDate: "2023-08-12 09:12:45",
+						Updated:    "2023-09-01 08:00:00",
+						Contacts:   []ClientContact{},
+					},
+				},
+				Meta: Meta{Page: 1, PerPage: 15, Pages: 1, Total: 2},
+			}),
+		},
+		{
+			fixture:  "client.json",
+			expected: accountingResponse(ClientResult{Client: testClient()}),
+		},
+		{
+			fixture: "staffs.json",
+			expected: accountingResponse(StaffsResult{
+				Staffs: []Staff{
+					{
+						ID:          1,
+						IdentityID:  8823411,
+						FirstName:   "Jane",
+						LastName:    "Doe",
+						Email:       "jane.doe@example.com",
+						Username:    "jdoe",
+						DisplayName: "Jane Doe",
+						LastLogin:   "2024-03-04 12:00:00",
+						SignupDate:  "2019-05-10 09:00:00",
+						Updated:     "2024-03-04 12:00:00",
+					},
+					{
+						ID:         4,
+						FirstName:  "Ravi",
+						LastName:   "Patel",
+						Email:      "ravi@example.com",
+						Username:   "rpatel",
+						Level:      1,
+						VisState:   1,
+						SignupDate: "2020-02-14 15:45:00",
+						Updated:    "2022-07-01 08:00:00",
+					},
+				},
+				Meta: Meta{Page: 1, PerPage: 15, Pages: 1, Total: 2},
+			}),
+		},
+		{
+			fixture: "callbacks.json",
+			expected: accountingResponse(CallbacksResult{
+				Callbacks: []Callback{
+					{CallbackID: 2001, Event: "client", URI: "https://hooks.example.com/freshbooks", Verified: true},
+					{CallbackID: 2002, Event: "project.create", URI: "https://hooks.example.com/freshbooks"},
+				},
+				Meta: Meta{Page: 1, PerPage: 100, Pages: 1, Total: 2},
+			}),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.fixture, func(t *testing.T) {
+			content, err := os.ReadFile(filepath.Join("testdata", tt.fixture))
+			require.NoError(t, err)
+
+			modelType := reflect.TypeOf(tt.expected)
+
+			got := reflect.New(modelType)
+			require.NoError(t, json.Unmarshal(content, got.Interface()))
+			assert.Equal(t, tt.expected, got.Elem().Interface())
+
+			if *strictDecode {
+				decoder := json.NewDecoder(bytes.NewReader(content))
+				decoder.DisallowUnknownFields()
+				err := decoder.Decode(reflect.New(modelType).Interface())
+				assert.NoError(t, err, "%s has fields that %s doesn't decode", tt.fixture, modelType)
+			}
+		})
+	}
+}
+
+func accountingResponse[T any](result T) AccountingResponse[T] {
+	var res AccountingResponse[T]
+	res.Response.Result = result
+
+	return res
+}
+
+func testClient() Client {
+	return Client{
+		ID:           301,
+		UserID:       301,
+		FirstName:    "Maria",
+		LastName:     "Garcia",
+		Email:        "maria@globex.example.com",
+		Organization: "Globex",
+		Username:     "mgarcia",
+		SignupDate:   "2023-06-01 10:00:00",
+		Updated:      "2024-04-15 17:30:12",
+		Contacts: []ClientContact{
+			{ID: 12, UserID: 4410, FirstName: "Tom", LastName: "Baker", Email: "tom@globex.example.com"},
+		},
+	}
+}
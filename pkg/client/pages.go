@@ -0,0 +1,111 @@
+package client
+
+import (
+	"context"
+	"sync"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"golang.org/x/sync/semaphore"
+)
+
+// DefaultPageConcurrency is the number of pages requested at the same time when every page of a list is fetched.
+const DefaultPageConcurrency = 4
+
+// WithPageConcurrency sets the number of pages requested at the same time when every page of a list is fetched.
+// A concurrency of 1 requests the pages one after the other.
+func WithPageConcurrency(concurrency int) Option {
+	return func(client *FreshBooksClient) {
+		client.pageConcurrency = max(concurrency, 1)
+	}
+}
+
+// pageFetcher requests a single page of a list, returning its items along with the pagination metadata.
+type pageFetcher[T any] func(ctx context.Context, page int) ([]T, Meta, annotations.Annotations, error)
+
+// fetchAllPages requests the first page of a list, and then the rest of them concurrently, since the number of pages
+// is known from the metadata of the first one. The items are returned in the same order as the pages.
+// No more requests are sent at the same time than the ones the rate limit has left, and the first error stops the
+// pages that weren't requested yet. The annotations carry the rate limit state of the last page.
+func fetchAllPages[T any](ctx context.Context, concurrency int, fetch pageFetcher[T]) ([]T, annotations.Annotations, error) {
+	firstPage, meta, annotation, err := fetch(ctx, 1)
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	pages := totalPages(meta)
+	if pages <= 1 {
+		return firstPage, annotation, nil
+	}
+
+	concurrency = min(max(concurrency, 1), pages-1)
+	rateLimit := &v2.RateLimitDescription{}
+	if ok, _ := annotation.Pick(rateLimit); ok && rateLimit.Limit > 0 {
+		concurrency = max(min(concurrency, int(rateLimit.Remaining)), 1)
+	}
+
+	results := make([][]T, pages)
+	results[0] = firstPage
+	pageAnnotations := make([]annotations.Annotations, pages)
+	pageAnnotations[0] = annotation
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		errOnce  sync.Once
+		fetchErr error
+		sem      = semaphore.NewWeighted(int64(concurrency))
+	)
+	for page := 2; page <= pages; page++ {
+		err := sem.Acquire(ctx, 1)
+		if err != nil {
+			errOnce.Do(func() { fetchErr = err })
+			break
+		}
+
+		wg.Add(1)
+		go func(page int) {
+			defer wg.Done()
+			defer sem.Release(1)
+
+			items, _, pageAnnotation, err := fetch(ctx, page)
+			if err != nil {
+				errOnce.Do(func() {
+					fetchErr = err
+					cancel()
+				})
+				return
+			}
+
+			results[page-1] = items
+			pageAnnotations[page-1] = pageAnnotation
+		}(page)
+	}
+	wg.Wait()
+
+	if fetchErr != nil {
+		return nil, nil, fetchErr
+	}
+
+	var ret []T
+	for _, items := range results {
+		ret = append(ret, items...)
+	}
+
+	return ret, pageAnnotations[pages-1], nil
+}
+
+// totalPages returns the number of pages of a list, from the page count when the API sends it or from the total.
+func totalPages(meta Meta) int {
+	if meta.Pages > 0 {
+		return meta.Pages
+	}
+
+	if meta.PerPage <= 0 {
+		return 1
+	}
+
+	return (meta.Total + meta.PerPage - 1) / meta.PerPage
+}
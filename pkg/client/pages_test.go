@@ -0,0 +1,92 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// pagesOf returns a fetcher of a list of total items, split in pages of perPage items, that reports the highest
+// number of pages being requested at the same time.
+func pagesOf(total, perPage int, remaining int64, inFlight, maxInFlight *atomic.Int32) pageFetcher[int] {
+	return func(_ context.Context, page int) ([]int, Meta, annotations.Annotations, error) {
+		current := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			highest := maxInFlight.Load()
+			if current <= highest || maxInFlight.CompareAndSwap(highest, current) {
+				break
+			}
+		}
+
+		// The later pages answer first, to check that the order is kept anyway.
+		time.Sleep(time.Duration(total/perPage-page+1) * time.Millisecond)
+
+		var items []int
+		for i := (page - 1) * perPage; i < min(page*perPage, total); i++ {
+			items = append(items, i)
+		}
+
+		annotation := annotations.Annotations{}
+		if remaining >= 0 {
+			annotation.WithRateLimiting(&v2.RateLimitDescription{Limit: 100, Remaining: remaining})
+		}
+
+		return items, Meta{Page: page, PerPage: perPage, Total: total}, annotation, nil
+	}
+}
+
+func TestFetchAllPagesKeepsOrder(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+
+	items, _, err := fetchAllPages(context.Background(), 3, pagesOf(95, 10, -1, &inFlight, &maxInFlight))
+	require.NoError(t, err)
+
+	require.Len(t, items, 95)
+	for i, item := range items {
+		assert.Equal(t, i, item)
+	}
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(3))
+	assert.Greater(t, maxInFlight.Load(), int32(1))
+}
+
+func TestFetchAllPagesFollowsRateLimit(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+
+	items, _, err := fetchAllPages(context.Background(), 8, pagesOf(50, 10, 1, &inFlight, &maxInFlight))
+	require.NoError(t, err)
+
+	assert.Len(t, items, 50)
+	assert.Equal(t, int32(1), maxInFlight.Load())
+}
+
+func TestFetchAllPagesSinglePage(t *testing.T) {
+	var inFlight, maxInFlight atomic.Int32
+
+	items, _, err := fetchAllPages(context.Background(), 4, pagesOf(7, 10, -1, &inFlight, &maxInFlight))
+	require.NoError(t, err)
+	assert.Len(t, items, 7)
+}
+
+func TestFetchAllPagesStopsOnError(t *testing.T) {
+	errPage := errors.New("page failed")
+	var requested atomic.Int32
+
+	_, _, err := fetchAllPages(context.Background(), 1, func(ctx context.Context, page int) ([]int, Meta, annotations.Annotations, error) {
+		requested.Add(1)
+		if page == 3 {
+			return nil, Meta{}, nil, errPage
+		}
+
+		return []int{page}, Meta{Page: page, PerPage: 1, Total: 10}, nil, nil
+	})
+	assert.ErrorIs(t, err, errPage)
+	assert.Equal(t, int32(3), requested.Load())
+}
@@ -3,6 +3,7 @@ package client
 import (
 	"net/url"
 	"strconv"
+	"time"
 )
 
 // The number of objects returned per page can be adjusted by adding the 'per_page' parameter in the query string.
@@ -16,6 +17,15 @@ type PageOptions struct {
 	Page    int `url:"page,omitempty"`
 }
 
+// nextPage returns the number of the page after the one described by the metadata, or an empty string if it was the last one.
+func nextPage(paginationData Meta) string {
+	if paginationData.Page*paginationData.PerPage < paginationData.Total {
+		return strconv.Itoa(paginationData.Page + 1)
+	}
+
+	return ""
+}
+
 type ReqOpt func(reqURL *url.URL)
 
 // WithPageLimit : Number of items to return.
@@ -35,6 +45,14 @@ func WithPage(page int) ReqOpt {
 	return WithQueryParam("page", strconv.Itoa(page))
 }
 
+// WithUpdatedSince : Only the objects created or updated at or after since. A zero time returns every object.
+func WithUpdatedSince(since time.Time) ReqOpt {
+	if since.IsZero() {
+		return func(*url.URL) {}
+	}
+	return WithQueryParam("updated_since", since.UTC().Format(time.RFC3339))
+}
+
 func WithQueryParam(key string, value string) ReqOpt {
 	return func(reqURL *url.URL) {
 		q := reqURL.Query()
@@ -42,3 +60,8 @@ func WithQueryParam(key string, value string) ReqOpt {
 		reqURL.RawQuery = q.Encode()
 	}
 }
+
+// WithIncludeContacts asks the accounting API to include the contacts of each client.
+func WithIncludeContacts() ReqOpt {
+	return WithQueryParam("include[]", "contacts")
+}
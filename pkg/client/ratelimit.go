@@ -0,0 +1,127 @@
+package client
+
+import (
+	"context"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/ratelimit"
+)
+
+const (
+	defaultMaxRetries  = 5
+	defaultBaseDelay   = 1 * time.Second
+	defaultMaxDelay    = 30 * time.Second
+	defaultRetryBudget = 2 * time.Minute
+)
+
+// RetryConfig defines how the requests rejected by FreshBooks for being over the rate limit
+// (or answered with a transient 502/503/504, for the idempotent methods) are retried.
+type RetryConfig struct {
+	// MaxRetries is the number of retries after the first attempt. 0 disables the retries.
+	MaxRetries int
+	// BaseDelay is the delay of the first retry, doubled on every retry after it.
+	BaseDelay time.Duration
+	// MaxDelay caps the delay between two attempts.
+	MaxDelay time.Duration
+	// Budget is the maximum time spent waiting between attempts for a single request.
+	Budget time.Duration
+}
+
+// DefaultRetryConfig returns the RetryConfig used when the client is not configured with WithRetryConfig.
+func DefaultRetryConfig() RetryConfig {
+	return RetryConfig{
+		MaxRetries: defaultMaxRetries,
+		BaseDelay:  defaultBaseDelay,
+		MaxDelay:   defaultMaxDelay,
+		Budget:     defaultRetryBudget,
+	}
+}
+
+// WithRetryConfig sets how many times and for how long the rate limited requests are retried.
+func WithRetryConfig(config RetryConfig) Option {
+	return func(client *FreshBooksClient) {
+		client.retryConfig = config
+	}
+}
+
+// shouldRetry reports whether a response to a request made with method is worth retrying after waiting.
+// A request over the rate limit wasn't processed, so it is always retried. A 502/503/504 may come after the request
+// was processed, so it is only retried for the idempotent methods, and a POST like an invitation isn't sent twice.
+func shouldRetry(method string, resp *http.Response) bool {
+	if resp == nil {
+		return false
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests:
+		return true
+	case http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return method == http.MethodGet || method == http.MethodPut || method == http.MethodDelete
+	}
+
+	return false
+}
+
+// retryDelay returns how long to wait before the given retry (starting at 0).
+// The Retry-After header sent by FreshBooks is honored, otherwise an exponential backoff with full jitter is used.
+func (c RetryConfig) retryDelay(resp *http.Response, retry int) time.Duration {
+	if resp != nil {
+		if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+			if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds >= 0 {
+				return min(time.Duration(seconds)*time.Second, c.MaxDelay)
+			}
+			if date, err := http.ParseTime(retryAfter); err == nil {
+				return min(max(time.Until(date), 0), c.MaxDelay)
+			}
+		}
+	}
+
+	backoff := c.BaseDelay << retry
+	if backoff <= 0 || backoff > c.MaxDelay {
+		backoff = c.MaxDelay
+	}
+
+	// Half of the backoff is fixed and the other half is random, so concurrent syncs don't retry in lockstep.
+	half := int64(backoff / 2)
+	if half <= 0 {
+		return backoff
+	}
+
+	return time.Duration(half + rand.Int64N(half)) // #nosec G404 -- jitter doesn't need a secure random source.
+}
+
+// waitForRetry blocks for the given delay, unless the context is done first.
+func waitForRetry(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// extractRateLimitData reads the rate limit headers of a FreshBooks response.
+// It returns nil when the response doesn't carry any rate limit information.
+func extractRateLimitData(resp *http.Response) *v2.RateLimitDescription {
+	if resp == nil {
+		return nil
+	}
+
+	description, err := ratelimit.ExtractRateLimitData(resp.StatusCode, &resp.Header)
+	if err != nil || description == nil {
+		return nil
+	}
+
+	if description.Limit == 0 && description.Remaining == 0 && description.Status == v2.RateLimitDescription_STATUS_UNSPECIFIED {
+		return nil
+	}
+
+	return description
+}
@@ -0,0 +1,112 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func newTestClient(t *testing.T, retryConfig RetryConfig) *FreshBooksClient {
+	c, err := New(context.Background(), WithBearerToken("token"), WithRetryConfig(retryConfig))
+	require.NoError(t, err)
+
+	return c
+}
+
+func TestDoRequestRetriesRateLimitedRequests(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if attempts.Add(1) < 3 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		w.Header().Set("X-Ratelimit-Limit", "100")
+		w.Header().Set("X-Ratelimit-Remaining", "42")
+		_, _ = w.Write([]byte(`{"response": [{"uuid": "abc"}]}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, RetryConfig{MaxRetries: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, Budget: time.Second})
+
+	var res Response
+	annos, err := c.doRequest(context.Background(), http.MethodGet, server.URL+"/team_members", &res, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(3), attempts.Load())
+	assert.Len(t, res.Response, 1)
+
+	rateLimit := &v2.RateLimitDescription{}
+	ok, err := annos.Pick(rateLimit)
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.Equal(t, int64(42), rateLimit.Remaining)
+}
+
+func TestDoRequestStopsRetryingAfterMaxRetries(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusTooManyRequests)
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, Budget: time.Second})
+
+	_, err := c.doRequest(context.Background(), http.MethodGet, server.URL+"/team_members", &Response{}, nil)
+	require.Error(t, err)
+	assert.Equal(t, int32(3), attempts.Load())
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestDoRequestOnlyRetriesIdempotentRequestsOnServerErrors(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		attempts.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, Budget: time.Second})
+
+	// The invitation may have been sent before the error, so it isn't sent again.
+	_, err := c.doRequest(context.Background(), http.MethodPost, server.URL+"/invitations", &Response{}, nil)
+	require.Error(t, err)
+	assert.Equal(t, int32(1), attempts.Load())
+
+	attempts.Store(0)
+	_, err = c.doRequest(context.Background(), http.MethodGet, server.URL+"/team_members", &Response{}, nil)
+	require.Error(t, err)
+	assert.Equal(t, int32(3), attempts.Load())
+}
+
+func TestDoRequestRetriesRateLimitedPosts(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if attempts.Add(1) < 2 {
+			w.Header().Set("Retry-After", "0")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+
+		_, _ = w.Write([]byte(`{"response": [{"uuid": "abc"}]}`))
+	}))
+	defer server.Close()
+
+	c := newTestClient(t, RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond, Budget: time.Second})
+
+	_, err := c.doRequest(context.Background(), http.MethodPost, server.URL+"/invitations", &Response{}, nil)
+	require.NoError(t, err)
+	assert.Equal(t, int32(2), attempts.Load())
+}
@@ -0,0 +1,201 @@
+package client
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	sealedFileVersion = 1
+	sealedFileMode    = 0o600
+	saltSize          = 16
+	keySize           = 32
+
+	// scrypt parameters recommended for interactive logins.
+	scryptN = 1 << 15
+	scryptR = 8
+	scryptP = 1
+)
+
+// SealedFile keeps a value in a file as JSON, encrypted with AES-GCM using a key derived from a passphrase.
+type SealedFile struct {
+	path       string
+	passphrase []byte
+
+	mutex sync.Mutex
+	// salt and gcm are the last derived key, reused by the next writes since deriving it is slow on purpose.
+	salt []byte
+	gcm  cipher.AEAD
+}
+
+// sealedFileContent is the content of the file written by SealedFile.
+type sealedFileContent struct {
+	Version    int    `json:"version"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// NewSealedFile returns a SealedFile that reads and writes the value in path.
+func NewSealedFile(path, passphrase string) (*SealedFile, error) {
+	if path == "" {
+		return nil, fmt.Errorf("sealed file path is empty")
+	}
+
+	if passphrase == "" {
+		return nil, fmt.Errorf("sealed file passphrase is empty")
+	}
+
+	return &SealedFile{
+		path:       path,
+		passphrase: []byte(passphrase),
+	}, nil
+}
+
+// Load decrypts the file into v. It returns false, and leaves v untouched, when the file doesn't exist yet.
+func (f *SealedFile) Load(v interface{}) (bool, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	content, err := os.ReadFile(f.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("error reading %s: %w", f.path, err)
+	}
+
+	var file sealedFileContent
+	err = json.Unmarshal(content, &file)
+	if err != nil {
+		return false, fmt.Errorf("error decoding %s: %w", f.path, err)
+	}
+
+	if file.Version != sealedFileVersion {
+		return false, fmt.Errorf("unsupported version %d of %s", file.Version, f.path)
+	}
+
+	gcm, err := f.cipher(file.Salt)
+	if err != nil {
+		return false, err
+	}
+
+	plaintext, err := gcm.Open(nil, file.Nonce, file.Ciphertext, nil)
+	if err != nil {
+		return false, fmt.Errorf("error decrypting %s, check the passphrase: %w", f.path, err)
+	}
+
+	err = json.Unmarshal(plaintext, v)
+	if err != nil {
+		return false, fmt.Errorf("error decoding the content of %s: %w", f.path, err)
+	}
+
+	return true, nil
+}
+
+// Save encrypts v into the file.
+func (f *SealedFile) Save(v interface{}) error {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	plaintext, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	salt := f.salt
+	if salt == nil {
+		salt = make([]byte, saltSize)
+		_, err = rand.Read(salt)
+		if err != nil {
+			return err
+		}
+	}
+
+	gcm, err := f.cipher(salt)
+	if err != nil {
+		return err
+	}
+
+	file := sealedFileContent{
+		Version: sealedFileVersion,
+		Salt:    salt,
+		Nonce:   make([]byte, gcm.NonceSize()),
+	}
+	_, err = rand.Read(file.Nonce)
+	if err != nil {
+		return err
+	}
+	file.Ciphertext = gcm.Seal(nil, file.Nonce, plaintext, nil)
+
+	content, err := json.Marshal(file)
+	if err != nil {
+		return err
+	}
+
+	// The value is written in a temporary file first, so a crash never leaves the file half written.
+	tmp, err := os.CreateTemp(filepath.Dir(f.path), filepath.Base(f.path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("error writing %s: %w", f.path, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing %s: %w", f.path, err)
+	}
+
+	err = os.Chmod(tmp.Name(), sealedFileMode)
+	if err != nil {
+		return fmt.Errorf("error writing %s: %w", f.path, err)
+	}
+
+	err = os.Rename(tmp.Name(), f.path)
+	if err != nil {
+		return fmt.Errorf("error writing %s: %w", f.path, err)
+	}
+
+	return nil
+}
+
+// cipher derives the encryption key from the passphrase and the salt, or reuses the last one derived for that
+// salt. The mutex must be held.
+func (f *SealedFile) cipher(salt []byte) (cipher.AEAD, error) {
+	if f.gcm != nil && bytes.Equal(f.salt, salt) {
+		return f.gcm, nil
+	}
+
+	key, err := scrypt.Key(f.passphrase, salt, scryptN, scryptR, scryptP, keySize)
+	if err != nil {
+		return nil, fmt.Errorf("error deriving the key of %s: %w", f.path, err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	f.salt = salt
+	f.gcm = gcm
+
+	return gcm, nil
+}
@@ -0,0 +1,100 @@
+package client
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"golang.org/x/oauth2"
+)
+
+// TokenStore persists the OAuth token between runs.
+// FreshBooks rotates the refresh token on every exchange, so the refresh token given
+// to the connector only works once unless the rotated one is saved somewhere.
+// Load must return a nil token and a nil error when nothing was stored yet.
+type TokenStore interface {
+	Load(ctx context.Context) (*oauth2.Token, error)
+	Save(ctx context.Context, token *oauth2.Token) error
+}
+
+// FileTokenStore is a TokenStore that keeps the token in a file, encrypted with AES-GCM
+// using a key derived from a passphrase.
+type FileTokenStore struct {
+	file *SealedFile
+}
+
+// NewFileTokenStore returns a FileTokenStore that reads and writes the token in path.
+func NewFileTokenStore(path, passphrase string) (*FileTokenStore, error) {
+	if path == "" {
+		return nil, fmt.Errorf("token store path is empty")
+	}
+
+	if passphrase == "" {
+		return nil, fmt.Errorf("token store passphrase is empty")
+	}
+
+	file, err := NewSealedFile(path, passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	return &FileTokenStore{file: file}, nil
+}
+
+func (s *FileTokenStore) Load(_ context.Context) (*oauth2.Token, error) {
+	var token oauth2.Token
+	ok, err := s.file.Load(&token)
+	if err != nil {
+		return nil, fmt.Errorf("error loading token store: %w", err)
+	}
+	if !ok {
+		return nil, nil
+	}
+
+	return &token, nil
+}
+
+func (s *FileTokenStore) Save(_ context.Context, token *oauth2.Token) error {
+	err := s.file.Save(token)
+	if err != nil {
+		return fmt.Errorf("error saving token store: %w", err)
+	}
+
+	return nil
+}
+
+// persistingTokenSource saves every token that comes with a new refresh token into the store.
+type persistingTokenSource struct {
+	ctx          context.Context
+	source       oauth2.TokenSource
+	store        TokenStore
+	refreshToken string
+	mutex        sync.Mutex
+}
+
+func (p *persistingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := p.source.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if token.RefreshToken == "" || token.RefreshToken == p.refreshToken {
+		return token, nil
+	}
+
+	err = p.store.Save(p.ctx, token)
+	if err != nil {
+		// The exchange already consumed the previous refresh token, the current run can go on with the new
+		// access token but the next one will need a new refresh token.
+		ctxzap.Extract(p.ctx).Error("error saving the rotated FreshBooks refresh token", zap.Error(err))
+		return token, nil
+	}
+	p.refreshToken = token.RefreshToken
+
+	return token, nil
+}
@@ -0,0 +1,80 @@
+package client
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+)
+
+func TestFileTokenStore(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "token.json")
+
+	store, err := NewFileTokenStore(path, "passphrase")
+	require.NoError(t, err)
+
+	token, err := store.Load(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, token)
+
+	expiry := time.Now().Add(time.Hour).Truncate(time.Second)
+	err = store.Save(ctx, &oauth2.Token{AccessToken: "access", RefreshToken: "refresh", Expiry: expiry})
+	require.NoError(t, err)
+
+	token, err = store.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "access", token.AccessToken)
+	assert.Equal(t, "refresh", token.RefreshToken)
+	assert.True(t, expiry.Equal(token.Expiry))
+
+	wrongStore, err := NewFileTokenStore(path, "wrong passphrase")
+	require.NoError(t, err)
+
+	_, err = wrongStore.Load(ctx)
+	assert.Error(t, err)
+}
+
+type rotatingTokenSource struct {
+	refreshTokens []string
+}
+
+func (r *rotatingTokenSource) Token() (*oauth2.Token, error) {
+	refreshToken := r.refreshTokens[0]
+	if len(r.refreshTokens) > 1 {
+		r.refreshTokens = r.refreshTokens[1:]
+	}
+
+	return &oauth2.Token{AccessToken: "access-" + refreshToken, RefreshToken: refreshToken}, nil
+}
+
+func TestPersistingTokenSourceSavesRotatedTokens(t *testing.T) {
+	ctx := context.Background()
+	store, err := NewFileTokenStore(filepath.Join(t.TempDir(), "token.json"), "passphrase")
+	require.NoError(t, err)
+
+	source := &persistingTokenSource{
+		ctx:          ctx,
+		source:       &rotatingTokenSource{refreshTokens: []string{"first", "second"}},
+		store:        store,
+		refreshToken: "first",
+	}
+
+	_, err = source.Token()
+	require.NoError(t, err)
+
+	stored, err := store.Load(ctx)
+	require.NoError(t, err)
+	assert.Nil(t, stored, "the configured refresh token must not be saved")
+
+	_, err = source.Token()
+	require.NoError(t, err)
+
+	stored, err = store.Load(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "second", stored.RefreshToken)
+}
@@ -0,0 +1,103 @@
+package client
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/conductorone/baton-sdk/pkg/uhttp"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+)
+
+// HTTPConfig sets how the client reaches the FreshBooks API. The zero value keeps the defaults: the proxy
+// from the environment, the system CA bundle and a timeout of 5 minutes per request.
+type HTTPConfig struct {
+	// ProxyURL is the proxy every request goes through, instead of the one from the environment.
+	ProxyURL string
+	// CAFile is a PEM bundle of CA certificates trusted along with the system ones, like the one of a TLS inspecting proxy.
+	CAFile string
+	// Timeout is the maximum time a request can take, including reading the response.
+	Timeout time.Duration
+}
+
+// WithHTTPConfig sets the proxy, the CA certificates and the timeout of the requests.
+// The access tokens are requested with the same settings.
+func WithHTTPConfig(httpConfig HTTPConfig) Option {
+	return func(client *FreshBooksClient) {
+		client.httpConfig = httpConfig
+	}
+}
+
+// newHTTPClient builds the http.Client used for every request, following the HTTP configuration.
+func newHTTPClient(ctx context.Context, httpConfig HTTPConfig) (*http.Client, error) {
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if httpConfig.CAFile != "" {
+		rootCAs, err := loadCAFile(httpConfig.CAFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	var (
+		httpClient *http.Client
+		err        error
+	)
+	if httpConfig.ProxyURL == "" {
+		httpClient, err = uhttp.NewClient(ctx, uhttp.WithLogger(true, ctxzap.Extract(ctx)), uhttp.WithTLSClientConfig(tlsConfig))
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		// uhttp always takes the proxy from the environment, so the transport is built here when one is set.
+		proxyURL, err := url.Parse(httpConfig.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %w", err)
+		}
+
+		transport, ok := http.DefaultTransport.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("unexpected default transport %T", http.DefaultTransport)
+		}
+		transport = transport.Clone()
+		transport.Proxy = http.ProxyURL(proxyURL)
+		transport.TLSClientConfig = tlsConfig
+
+		httpClient = &http.Client{
+			Transport: transport,
+			Timeout:   5 * time.Minute,
+		}
+	}
+
+	if httpConfig.Timeout > 0 {
+		httpClient.Timeout = httpConfig.Timeout
+	}
+
+	return httpClient, nil
+}
+
+// loadCAFile returns the system CA certificates along with the ones of the PEM file.
+func loadCAFile(path string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the CA file: %w", err)
+	}
+
+	rootCAs, err := x509.SystemCertPool()
+	if err != nil {
+		rootCAs = x509.NewCertPool()
+	}
+
+	if !rootCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("the CA file %s has no PEM certificates", path)
+	}
+
+	return rootCAs, nil
+}
@@ -0,0 +1,70 @@
+package client
+
+import (
+	"context"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const identityResponse = `{"response": {"id": 1, "business_memberships": [{"id": 1, "business": {"id": 42, "name": "Acme"}}]}}`
+
+func TestHTTPConfigProxy(t *testing.T) {
+	var proxiedHost string
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxiedHost = r.Host
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(identityResponse))
+	}))
+	defer proxy.Close()
+
+	c, err := New(
+		context.Background(),
+		WithBearerToken("token"),
+		WithBaseURL("http://freshbooks.invalid"),
+		WithHTTPConfig(HTTPConfig{ProxyURL: proxy.URL}),
+	)
+	require.NoError(t, err)
+
+	businesses, err := c.RequestBusinesses(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, businesses, 1)
+	assert.Equal(t, "freshbooks.invalid", proxiedHost)
+}
+
+func TestHTTPConfigCAFile(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(identityResponse))
+	}))
+	defer server.Close()
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	certificate := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	require.NoError(t, os.WriteFile(caFile, certificate, 0o600))
+
+	untrusted, err := New(context.Background(), WithBearerToken("token"), WithBaseURL(server.URL), WithRetryConfig(RetryConfig{}))
+	require.NoError(t, err)
+	_, err = untrusted.RequestBusinesses(context.Background())
+	assert.Error(t, err)
+
+	trusted, err := New(context.Background(), WithBearerToken("token"), WithBaseURL(server.URL), WithHTTPConfig(HTTPConfig{CAFile: caFile}))
+	require.NoError(t, err)
+	businesses, err := trusted.RequestBusinesses(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, businesses, 1)
+}
+
+func TestHTTPConfigInvalidCAFile(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte("not a certificate"), 0o600))
+
+	_, err := New(context.Background(), WithBearerToken("token"), WithHTTPConfig(HTTPConfig{CAFile: caFile}))
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package client
+
+import (
+	"fmt"
+	"net/url"
+)
+
+// The FreshBooks API is split in families, each one scoped by a different identifier:
+//   - auth: /auth/api/v1/..., with the business scoped endpoints under /auth/api/v1/businesses/{business_id}.
+//   - projects: /projects/business/{business_id}/...
+//   - accounting: /accounting/account/{account_id}/..., where account_id is the accounting account of the business.
+//   - events: /events/account/{account_id}/..., the webhook callbacks, scoped by the accounting account as well.
+
+// authURL builds the URL of an endpoint of the auth API.
+func (f *FreshBooksClient) authURL(elem ...string) (string, error) {
+	return url.JoinPath(f.baseURL, append([]string{authPath}, elem...)...)
+}
+
+// businessURL builds the URL of an endpoint of the auth API scoped to a business.
+func (f *FreshBooksClient) businessURL(businessID string, elem ...string) (string, error) {
+	if businessID == "" {
+		return "", fmt.Errorf("business ID is empty")
+	}
+
+	return url.JoinPath(f.baseURL, append([]string{authPath, businessBaseURL, businessID}, elem...)...)
+}
+
+// projectsURL builds the URL of an endpoint of the projects API, which is scoped to a business.
+func (f *FreshBooksClient) projectsURL(businessID string, elem ...string) (string, error) {
+	if businessID == "" {
+		return "", fmt.Errorf("business ID is empty")
+	}
+
+	return url.JoinPath(f.baseURL, append([]string{projectsBaseURL, businessID}, elem...)...)
+}
+
+// accountingURL builds the URL of an endpoint of the accounting API, which is scoped to an accounting account.
+func (f *FreshBooksClient) accountingURL(accountID string, elem ...string) (string, error) {
+	if accountID == "" {
+		return "", fmt.Errorf("account ID is empty")
+	}
+
+	return url.JoinPath(f.baseURL, append([]string{accountingBaseURL, accountID}, elem...)...)
+}
+
+// eventsURL builds the URL of an endpoint of the events API, which is scoped to an accounting account.
+func (f *FreshBooksClient) eventsURL(accountID string, elem ...string) (string, error) {
+	if accountID == "" {
+		return "", fmt.Errorf("account ID is empty")
+	}
+
+	return url.JoinPath(f.baseURL, append([]string{eventsBaseURL, accountID}, elem...)...)
+}
@@ -0,0 +1,83 @@
+package client
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestURLBuilders(t *testing.T) {
+	c := newTestClient(t, DefaultRetryConfig())
+
+	tests := []struct {
+		name     string
+		build    func() (string, error)
+		expected string
+	}{
+		{
+			name:     "auth",
+			build:    func() (string, error) { return c.authURL(getBusinessID) },
+			expected: "https://api.freshbooks.com/auth/api/v1/users/me",
+		},
+		{
+			name:     "business",
+			build:    func() (string, error) { return c.businessURL("123", getTeamMembers) },
+			expected: "https://api.freshbooks.com/auth/api/v1/businesses/123/team_members",
+		},
+		{
+			name:     "projects",
+			build:    func() (string, error) { return c.projectsURL("123", getProject, "7") },
+			expected: "https://api.freshbooks.com/projects/business/123/project/7",
+		},
+		{
+			name:     "accounting",
+			build:    func() (string, error) { return c.accountingURL("xZNQ1X", getClients) },
+			expected: "https://api.freshbooks.com/accounting/account/xZNQ1X/users/clients",
+		},
+		{
+			name:     "events",
+			build:    func() (string, error) { return c.eventsURL("xZNQ1X", getCallbacks, "42") },
+			expected: "https://api.freshbooks.com/events/account/xZNQ1X/events/callbacks/42",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.build()
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, got)
+		})
+	}
+}
+
+func TestURLBuildersRequireID(t *testing.T) {
+	c := newTestClient(t, DefaultRetryConfig())
+
+	_, err := c.businessURL("", getTeamMembers)
+	assert.Error(t, err)
+
+	_, err = c.projectsURL("", getProjects)
+	assert.Error(t, err)
+
+	_, err = c.accountingURL("", getClients)
+	assert.Error(t, err)
+
+	_, err = c.eventsURL("", getCallbacks)
+	assert.Error(t, err)
+}
+
+func TestURLBuildersUseBaseURL(t *testing.T) {
+	c, err := New(context.Background(), WithBearerToken("token"), WithBaseURL("http://127.0.0.1:8080/"))
+	require.NoError(t, err)
+
+	got, err := c.businessURL("123", getTeamMembers)
+	require.NoError(t, err)
+	assert.Equal(t, "http://127.0.0.1:8080/auth/api/v1/businesses/123/team_members", got)
+}
+
+func TestOAuthConfigTokenURL(t *testing.T) {
+	assert.Equal(t, "https://api.freshbooks.com/auth/oauth/token", NewOAuthConfig("", "id", "secret", "").Endpoint.TokenURL)
+	assert.Equal(t, "http://127.0.0.1:8080/auth/oauth/token", NewOAuthConfig("http://127.0.0.1:8080/", "id", "secret", "").Endpoint.TokenURL)
+}
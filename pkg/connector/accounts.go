@@ -0,0 +1,52 @@
+package connector
+
+import (
+	"strings"
+)
+
+// accountantRoleName is the role FreshBooks gives to the accountants invited to a business, usually bookkeeping
+// firms rather than employees.
+const accountantRoleName = "no_seat_employee"
+
+// accountClassifier tells the users of a business apart from the external ones, by the domain of their email.
+type accountClassifier struct {
+	corporateDomains []string
+}
+
+// newAccountClassifier returns a classifier for the corporate domains, given with or without a leading "@".
+// An entry can hold several domains separated by commas, like the value of an environment variable.
+func newAccountClassifier(corporateDomains []string) accountClassifier {
+	var domains []string
+	for _, entry := range corporateDomains {
+		for _, domain := range strings.Split(entry, ",") {
+			domain = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(domain), "@"))
+			if domain != "" {
+				domains = append(domains, domain)
+			}
+		}
+	}
+
+	return accountClassifier{corporateDomains: domains}
+}
+
+// isExternal reports whether a user is external to the business. With corporate domains, the users whose email
+// isn't on one of them or on one of their subdomains are external, including the ones without an email.
+// Without corporate domains, only the accountants are.
+func (c accountClassifier) isExternal(email, businessRoleName string) bool {
+	if len(c.corporateDomains) == 0 {
+		return businessRoleName == accountantRoleName
+	}
+
+	_, domain, ok := strings.Cut(strings.ToLower(email), "@")
+	if !ok || domain == "" {
+		return true
+	}
+
+	for _, corporateDomain := range c.corporateDomains {
+		if domain == corporateDomain || strings.HasSuffix(domain, "."+corporateDomain) {
+			return false
+		}
+	}
+
+	return true
+}
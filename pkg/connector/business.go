@@ -0,0 +1,85 @@
+package connector
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+)
+
+type businessBuilder struct {
+	resourceType *v2.ResourceType
+	client       *client.FreshBooksClient
+}
+
+func (b *businessBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return businessResourceType
+}
+
+// List returns every business the identity behind the token is a member of.
+// Users, roles, projects, clients and client contacts are synced as children of each business.
+func (b *businessBuilder) List(ctx context.Context, _ *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	err := b.client.EnsureBusinesses(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var ret []*v2.Resource
+	for _, business := range b.client.Businesses() {
+		businessResource, err := parseIntoBusinessResource(business)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		ret = append(ret, businessResource)
+	}
+
+	return ret, "", nil, nil
+}
+
+// Entitlements always returns an empty slice for businesses.
+func (b *businessBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for businesses since they don't have any entitlements.
+func (b *businessBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// parseIntoBusinessResource parses a Business from FreshBooks into a Business Resource.
+func parseIntoBusinessResource(business client.Business) (*v2.Resource, error) {
+	displayName := business.Name
+	if displayName == "" {
+		displayName = business.BusinessUUID
+	}
+
+	ret, err := rs.NewResource(
+		displayName,
+		businessResourceType,
+		strconv.FormatInt(business.ID, 10),
+		rs.WithAnnotation(
+			&v2.ChildResourceType{ResourceTypeId: userResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: roleResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: projectResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: clientResourceType.Id},
+			&v2.ChildResourceType{ResourceTypeId: clientContactResourceType.Id},
+		),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func newBusinessBuilder(c *client.FreshBooksClient) *businessBuilder {
+	return &businessBuilder{
+		resourceType: businessResourceType,
+		client:       c,
+	}
+}
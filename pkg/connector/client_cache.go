@@ -0,0 +1,75 @@
+package connector
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+)
+
+// clientCache keeps the clients of each business, along with their contacts, so the clients and the client contacts
+// of a sync are built from a single pass over the clients API. They are requested again once the TTL expires.
+type clientCache struct {
+	client *client.FreshBooksClient
+	ttl    time.Duration
+	now    func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*clientEntry
+}
+
+// clientEntry holds the clients of a business. Its mutex is held while they are requested, so the builders asking
+// for the same business at the same time wait for a single request.
+type clientEntry struct {
+	mu        sync.Mutex
+	clients   []client.Client
+	fetchedAt time.Time
+	valid     bool
+}
+
+func newClientCache(c *client.FreshBooksClient, ttl time.Duration) *clientCache {
+	return &clientCache{
+		client:  c,
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]*clientEntry),
+	}
+}
+
+// Get returns every client of a business, requesting them when they aren't kept or have expired. A business without
+// an accounting account has no clients, and returns client.ErrNoAccountingAccount.
+// The annotations carry the rate limit state of the last page requested, if any was.
+func (c *clientCache) Get(ctx context.Context, businessID string) ([]client.Client, annotations.Annotations, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[businessID]
+	if !ok {
+		entry = &clientEntry{}
+		c.entries[businessID] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	if entry.valid && c.now().Sub(entry.fetchedAt) < c.ttl {
+		return entry.clients, nil, nil
+	}
+
+	accountID, err := c.client.AccountID(ctx, businessID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	clients, annotation, err := c.client.ListAllClients(ctx, accountID)
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	entry.clients = clients
+	entry.fetchedAt = c.now()
+	entry.valid = true
+
+	return clients, annotation, nil
+}
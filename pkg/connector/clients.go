@@ -0,0 +1,372 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	clientMemberEntitlement = "member"
+
+	// clientVisStateActive is the vis_state of the clients that are neither deleted nor archived.
+	clientVisStateActive = 0
+)
+
+type clientBuilder struct {
+	resourceType *v2.ResourceType
+	clients      *clientCache
+	client       *client.FreshBooksClient
+}
+
+func (c *clientBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return clientResourceType
+}
+
+// List returns the clients (customers) of a business, from the cache shared with the client contacts.
+func (c *clientBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var rv []*v2.Resource
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	clients, nextPageToken, annotation, err := listClientsPage(ctx, c.clients, parentResourceID, pToken, clientResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, fbClient := range clients {
+		clientResource, err := parseIntoClientResource(fbClient, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, clientResource)
+	}
+
+	return rv, nextPageToken, annotation, nil
+}
+
+// Entitlements returns the access to the client portal of the client.
+func (c *clientBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	ret := []*v2.Entitlement{
+		entitlement.NewAssignmentEntitlement(
+			resource,
+			clientMemberEntitlement,
+			entitlement.WithGrantableTo(clientContactResourceType),
+			entitlement.WithDescription(fmt.Sprintf("Contact of the %s client, with access to its invoices and estimates in the client portal", resource.DisplayName)),
+			entitlement.WithDisplayName(fmt.Sprintf("%s client contact", resource.DisplayName)),
+		),
+	}
+
+	return ret, "", nil, nil
+}
+
+// Grants returns a grant for the primary contact of the client and for each one of its additional contacts.
+// The client comes from the cache, and is only requested on its own when it isn't in it anymore.
+func (c *clientBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	var ret []*v2.Grant
+
+	businessID := resource.ParentResourceId.Resource
+	clients, annotation, err := c.clients.Get(ctx, businessID)
+	if err != nil {
+		return nil, "", annotation, err
+	}
+
+	fbClient, ok := findClient(clients, resource.Id.Resource)
+	if !ok {
+		accountID, err := c.client.AccountID(ctx, businessID)
+		if err != nil {
+			return nil, "", annotation, err
+		}
+
+		requestedClient, _, err := c.client.GetClient(ctx, accountID, resource.Id.Resource)
+		if err != nil {
+			return nil, "", annotation, err
+		}
+		fbClient = *requestedClient
+	}
+
+	for _, contactID := range clientContactIDs(fbClient) {
+		principalID, err := rs.NewResourceID(clientContactResourceType, contactID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		ret = append(ret, grant.NewGrant(resource, clientMemberEntitlement, principalID))
+	}
+
+	return ret, "", annotation, nil
+}
+
+type clientContactBuilder struct {
+	resourceType *v2.ResourceType
+	clients      *clientCache
+	client       *client.FreshBooksClient
+}
+
+func (c *clientContactBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return clientContactResourceType
+}
+
+// List returns the people that can log into the client portal of a business: the primary contact of each client
+// and its additional contacts.
+func (c *clientContactBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var rv []*v2.Resource
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	clients, nextPageToken, annotation, err := listClientsPage(ctx, c.clients, parentResourceID, pToken, clientContactResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, fbClient := range clients {
+		contactResources, err := parseIntoClientContactResources(fbClient, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		rv = append(rv, contactResources...)
+	}
+
+	return rv, nextPageToken, annotation, nil
+}
+
+// Entitlements always returns an empty slice for client contacts.
+func (c *clientContactBuilder) Entitlements(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// Grants always returns an empty slice for client contacts since they don't have any entitlements.
+func (c *clientContactBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	return nil, "", nil, nil
+}
+
+// listClientsPage returns a page of the clients of the business, from the cache shared by the clients and the client
+// contacts, along with the token of the next page. The clients are paged through in memory, the page token being the
+// offset of the next page.
+func listClientsPage(
+	ctx context.Context,
+	clients *clientCache,
+	parentResourceID *v2.ResourceId,
+	pToken *pagination.Token,
+	resourceType *v2.ResourceType,
+) ([]client.Client, string, annotations.Annotations, error) {
+	bag, offset, err := getToken(pToken, resourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	fbClients, annotation, err := clients.Get(ctx, parentResourceID.Resource)
+	if errors.Is(err, client.ErrNoAccountingAccount) {
+		ctxzap.Extract(ctx).Debug("business has no accounting account, skipping its clients", zap.String("business_id", parentResourceID.Resource))
+		return nil, "", nil, nil
+	}
+	if err != nil {
+		return nil, "", annotation, err
+	}
+
+	pageSize := pToken.Size
+	if pageSize <= 0 {
+		pageSize = client.ItemsPerPage
+	}
+
+	end := min(offset+pageSize, len(fbClients))
+	offset = min(offset, end)
+
+	nextPage := ""
+	if end < len(fbClients) {
+		nextPage = strconv.Itoa(end)
+	}
+
+	err = bag.Next(nextPage)
+	if err != nil {
+		return nil, "", annotation, err
+	}
+
+	nextPageToken, err := bag.Marshal()
+	if err != nil {
+		return nil, "", annotation, err
+	}
+
+	return fbClients[offset:end], nextPageToken, annotation, nil
+}
+
+// findClient returns the client with the given ID.
+func findClient(clients []client.Client, clientID string) (client.Client, bool) {
+	for _, fbClient := range clients {
+		if strconv.FormatInt(fbClient.ID, 10) == clientID {
+			return fbClient, true
+		}
+	}
+
+	return client.Client{}, false
+}
+
+// clientContactIDs returns the IDs of the Client Contact Resources of a client.
+// The primary contact uses the ID of the client, and the additional contacts are prefixed by it.
+func clientContactIDs(fbClient client.Client) []string {
+	clientID := strconv.FormatInt(fbClient.ID, 10)
+
+	ret := []string{clientID}
+	for _, contact := range fbClient.Contacts {
+		ret = append(ret, clientID+":"+strconv.FormatInt(contact.ID, 10))
+	}
+
+	return ret
+}
+
+// parseIntoClientResource parses a Client from FreshBooks into a Client Resource of the given business.
+func parseIntoClientResource(fbClient client.Client, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"id":           fbClient.ID,
+		"organization": fbClient.Organization,
+		"email":        fbClient.Email,
+		"vis_state":    fbClient.VisState,
+		"business_id":  parentResourceID.Resource,
+	}
+
+	groupTraits := []rs.GroupTraitOption{
+		rs.WithGroupProfile(profile),
+	}
+
+	ret, err := rs.NewGroupResource(
+		clientDisplayName(fbClient),
+		clientResourceType,
+		fbClient.ID,
+		groupTraits,
+		rs.WithParentResourceID(parentResourceID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+// parseIntoClientContactResources parses the primary contact and the additional contacts of a Client from FreshBooks
+// into Client Contact Resources of the given business. Like the users, they are people, and since they are the
+// customers of the business they are flagged as external whatever their email.
+func parseIntoClientContactResources(fbClient client.Client, parentResourceID *v2.ResourceId) ([]*v2.Resource, error) {
+	ids := clientContactIDs(fbClient)
+
+	primary := client.ClientContact{
+		ID:        fbClient.ID,
+		UserID:    fbClient.UserID,
+		FirstName: fbClient.FirstName,
+		LastName:  fbClient.LastName,
+		Email:     fbClient.Email,
+	}
+	contacts := append([]client.ClientContact{primary}, fbClient.Contacts...)
+
+	ret := make([]*v2.Resource, 0, len(contacts))
+	for i, contact := range contacts {
+		contactResource, err := parseIntoClientContactResource(ids[i], contact, fbClient, i == 0, parentResourceID)
+		if err != nil {
+			return nil, err
+		}
+
+		ret = append(ret, contactResource)
+	}
+
+	return ret, nil
+}
+
+func parseIntoClientContactResource(
+	id string,
+	contact client.ClientContact,
+	fbClient client.Client,
+	primary bool,
+	parentResourceID *v2.ResourceId,
+) (*v2.Resource, error) {
+	var userStatus = v2.UserTrait_Status_STATUS_ENABLED
+	if fbClient.VisState != clientVisStateActive {
+		userStatus = v2.UserTrait_Status_STATUS_DISABLED
+	}
+
+	profile := map[string]interface{}{
+		"email":        contact.Email,
+		"first_name":   contact.FirstName,
+		"last_name":    contact.LastName,
+		"client_id":    fbClient.ID,
+		"organization": fbClient.Organization,
+		"primary":      primary,
+		"external":     true,
+	}
+
+	userTraits := []rs.UserTraitOption{
+		rs.WithUserProfile(profile),
+		rs.WithStatus(userStatus),
+		rs.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_HUMAN),
+	}
+	if contact.Email != "" {
+		userTraits = append(userTraits, rs.WithUserLogin(contact.Email), rs.WithEmail(contact.Email, true))
+	}
+
+	displayName := strings.TrimSpace(contact.FirstName + " " + contact.LastName)
+	if displayName == "" {
+		displayName = contact.Email
+	}
+	if displayName == "" {
+		displayName = clientDisplayName(fbClient)
+	}
+
+	ret, err := rs.NewUserResource(
+		displayName,
+		clientContactResourceType,
+		id,
+		userTraits,
+		rs.WithParentResourceID(parentResourceID),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func clientDisplayName(fbClient client.Client) string {
+	if fbClient.Organization != "" {
+		return fbClient.Organization
+	}
+
+	if name := strings.TrimSpace(fbClient.FirstName + " " + fbClient.LastName); name != "" {
+		return name
+	}
+
+	if fbClient.Email != "" {
+		return fbClient.Email
+	}
+
+	return strconv.FormatInt(fbClient.ID, 10)
+}
+
+func newClientBuilder(c *client.FreshBooksClient, clients *clientCache) *clientBuilder {
+	return &clientBuilder{
+		resourceType: clientResourceType,
+		clients:      clients,
+		client:       c,
+	}
+}
+
+func newClientContactBuilder(c *client.FreshBooksClient, clients *clientCache) *clientContactBuilder {
+	return &clientContactBuilder{
+		resourceType: clientContactResourceType,
+		clients:      clients,
+		client:       c,
+	}
+}
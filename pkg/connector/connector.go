@@ -2,18 +2,34 @@ package connector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"time"
 
 	"github.com/conductorone/baton-freshbooks/pkg/client"
+	"github.com/conductorone/baton-freshbooks/pkg/webhooks"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
 	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type Connector struct {
-	client *client.FreshBooksClient
+	client             *client.FreshBooksClient
+	clientOpts         []client.Option
+	defaultRole        string
+	rolePermissions    bool
+	accountClassifier  accountClassifier
+	teamMembers        *teamMemberCache
+	clients            *clientCache
+	teamMemberCacheTTL time.Duration
+	teamMemberState    *teamMemberState
+	eventState         *teamMemberState
+	webhookQueue       *webhooks.Queue
 }
 
 type Option func(*Connector) error
@@ -21,34 +37,136 @@ type Option func(*Connector) error
 // ResourceSyncers returns a ResourceSyncer for each resource type that should be synced from the upstream service.
 func (d *Connector) ResourceSyncers(_ context.Context) []connectorbuilder.ResourceSyncer {
 	return []connectorbuilder.ResourceSyncer{
-		newUserBuilder(d.client),
-		newRoleBuilder(d.client),
+		newBusinessBuilder(d.client),
+		newUserBuilder(d.client, d.teamMembers, d.defaultRole, d.accountClassifier),
+		newRoleBuilder(d.client, d.teamMembers, d.defaultRole, d.rolePermissions),
+		newProjectBuilder(d.client, d.teamMembers),
+		newClientBuilder(d.client, d.clients),
+		newClientContactBuilder(d.client, d.clients),
 	}
 }
 
 func WithRefreshToken(ctx context.Context, refreshToken, clientID, clientSecret string) Option {
 	return func(c *Connector) error {
-		clientOpts := []client.Option{
-			client.WithRefreshToken(ctx, refreshToken, clientID, clientSecret),
+		c.clientOpts = append(c.clientOpts, client.WithRefreshToken(ctx, refreshToken, clientID, clientSecret))
+		return nil
+	}
+}
+
+func WithAccessToken(_ context.Context, accessToken string) Option {
+	return func(c *Connector) error {
+		c.clientOpts = append(c.clientOpts, client.WithBearerToken(accessToken))
+		return nil
+	}
+}
+
+// WithTokenStore persists the refresh tokens rotated by FreshBooks, so they can be used on the next run.
+func WithTokenStore(store client.TokenStore) Option {
+	return func(c *Connector) error {
+		c.clientOpts = append(c.clientOpts, client.WithTokenStore(store))
+		return nil
+	}
+}
+
+// WithRetryConfig sets how the requests rejected by the FreshBooks rate limit are retried.
+func WithRetryConfig(retryConfig client.RetryConfig) Option {
+	return func(c *Connector) error {
+		if retryConfig.MaxRetries < 0 || retryConfig.Budget < 0 {
+			return fmt.Errorf("error applying option WithRetryConfig: retries and budget must not be negative")
 		}
-		fbc, err := client.New(ctx, clientOpts...)
-		if err != nil {
-			return fmt.Errorf("error applying option WithRefreshToken: %w", err)
+
+		c.clientOpts = append(c.clientOpts, client.WithRetryConfig(retryConfig))
+		return nil
+	}
+}
+
+// WithBaseURL sends the requests to another host instead of the FreshBooks API, like a recording proxy.
+func WithBaseURL(baseURL string) Option {
+	return func(c *Connector) error {
+		c.clientOpts = append(c.clientOpts, client.WithBaseURL(baseURL))
+		return nil
+	}
+}
+
+// WithHTTPConfig sets the proxy, the CA certificates and the timeout of the requests to FreshBooks.
+func WithHTTPConfig(httpConfig client.HTTPConfig) Option {
+	return func(c *Connector) error {
+		if httpConfig.Timeout < 0 {
+			return fmt.Errorf("error applying option WithHTTPConfig: the timeout must not be negative")
 		}
 
-		c.client = fbc
+		c.clientOpts = append(c.clientOpts, client.WithHTTPConfig(httpConfig))
 		return nil
 	}
 }
 
-func WithAccessToken(ctx context.Context, accessToken string) Option {
+// WithPageConcurrency sets the number of pages requested at the same time when every team member of a business is listed.
+func WithPageConcurrency(concurrency int) Option {
 	return func(c *Connector) error {
-		fbc, err := client.New(ctx, client.WithBearerToken(accessToken))
+		if concurrency < 1 {
+			return fmt.Errorf("error applying option WithPageConcurrency: the concurrency must be at least 1")
+		}
+
+		c.clientOpts = append(c.clientOpts, client.WithPageConcurrency(concurrency))
+		return nil
+	}
+}
+
+// WithDefaultRole sets the role the users are moved to when their role is revoked.
+func WithDefaultRole(businessRoleName string) Option {
+	return func(c *Connector) error {
+		if businessRoleName == ownerRoleName || !isAvailableRole(businessRoleName) {
+			return fmt.Errorf("error applying option WithDefaultRole: %q is not a role that can be granted", businessRoleName)
+		}
+
+		c.defaultRole = businessRoleName
+		return nil
+	}
+}
+
+// WithRolePermissions adds a permission entitlement to each known role for what it lets its team members do. The
+// permissions come from a table maintained by hand, which only approximates what FreshBooks allows, so they are off
+// by default.
+func WithRolePermissions(enabled bool) Option {
+	return func(c *Connector) error {
+		c.rolePermissions = enabled
+		return nil
+	}
+}
+
+// WithCorporateDomains sets the email domains of the business, the users with an email on another domain are flagged
+// as external in their profile. Without them, only the accountants are flagged as external.
+func WithCorporateDomains(domains []string) Option {
+	return func(c *Connector) error {
+		c.accountClassifier = newAccountClassifier(domains)
+		return nil
+	}
+}
+
+// WithTeamMemberCacheTTL sets how long the team members of a business are reused by the users, roles and projects
+// before they are requested again. A TTL of zero requests them every time.
+func WithTeamMemberCacheTTL(ttl time.Duration) Option {
+	return func(c *Connector) error {
+		if ttl < 0 {
+			return fmt.Errorf("error applying option WithTeamMemberCacheTTL: the TTL must not be negative")
+		}
+
+		c.teamMemberCacheTTL = ttl
+		return nil
+	}
+}
+
+// WithTeamMemberStatePath keeps the team members of each business in a file between syncs, so the next sync only
+// requests the team members updated since the previous one. Every team member is still requested once a day.
+// The file is encrypted with a key derived from passphrase.
+func WithTeamMemberStatePath(path, passphrase string) Option {
+	return func(c *Connector) error {
+		state, err := newTeamMemberState(path, passphrase)
 		if err != nil {
-			return fmt.Errorf("error applying option WithAccessToken: %w", err)
+			return fmt.Errorf("error applying option WithTeamMemberStatePath: %w", err)
 		}
 
-		c.client = fbc
+		c.teamMemberState = state
 		return nil
 	}
 }
@@ -64,18 +182,108 @@ func (d *Connector) Metadata(ctx context.Context) (*v2.ConnectorMetadata, error)
 	return &v2.ConnectorMetadata{
 		DisplayName: "Baton-FreshBooks Connector",
 		Description: "Connector to sync data from the FreshBooks Platform",
+		AccountCreationSchema: &v2.ConnectorAccountCreationSchema{
+			FieldMap: map[string]*v2.ConnectorAccountCreationSchema_Field{
+				"email": {
+					DisplayName: "Email",
+					Required:    true,
+					Description: "The email the invitation to join the business is sent to.",
+					Placeholder: "email@example.com",
+					Order:       1,
+					Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+						StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+					},
+				},
+				"first_name": {
+					DisplayName: "First name",
+					Required:    false,
+					Description: "The first name of the team member.",
+					Placeholder: "First name",
+					Order:       2,
+					Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+						StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+					},
+				},
+				"last_name": {
+					DisplayName: "Last name",
+					Required:    false,
+					Description: "The last name of the team member.",
+					Placeholder: "Last name",
+					Order:       3,
+					Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+						StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+					},
+				},
+				"role": {
+					DisplayName: "Role",
+					Required:    false,
+					Description: "The role of the team member: business_manager, business_employee, contractor or no_seat_employee.",
+					Placeholder: d.defaultRole,
+					Order:       4,
+					Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+						StringField: &v2.ConnectorAccountCreationSchema_StringField{
+							DefaultValue: &d.defaultRole,
+						},
+					},
+				},
+				"business_id": {
+					DisplayName: "Business ID",
+					Required:    false,
+					Description: "The ID of the business the team member is invited to, required when the identity is a member of several businesses.",
+					Placeholder: "Business ID",
+					Order:       5,
+					Field: &v2.ConnectorAccountCreationSchema_Field_StringField{
+						StringField: &v2.ConnectorAccountCreationSchema_StringField{},
+					},
+				},
+			},
+		},
 	}, nil
 }
 
 // Validate is called to ensure that the connector is properly configured. It should exercise any API credentials
 // to be sure that they are valid.
+// It requests the identity behind the token and checks that it can list the team members of each of its businesses.
+// The role of the identity, from the memberships FreshBooks returns with it, is only used to explain a refusal.
 func (d *Connector) Validate(ctx context.Context) (annotations.Annotations, error) {
+	err := d.client.EnsureBusinesses(ctx)
+	switch {
+	case errors.Is(err, client.ErrNoBusinessMemberships):
+		return nil, status.Error(codes.FailedPrecondition, "the FreshBooks identity is not a member of any business: use the credentials of a business owner or admin")
+	case status.Code(err) == codes.Unauthenticated:
+		return nil, fmt.Errorf("the FreshBooks credentials were rejected, check that the token or the refresh token, client ID and client secret are valid and not revoked: %w", err)
+	case err != nil:
+		return nil, fmt.Errorf("error requesting the FreshBooks identity: %w", err)
+	}
+
+	for _, business := range d.client.Businesses() {
+		businessID := strconv.FormatInt(business.ID, 10)
+		_, _, _, err := d.client.ListTeamMembers(ctx, businessID, client.PageOptions{Page: 1, PerPage: 1})
+		switch {
+		case status.Code(err) == codes.PermissionDenied:
+			role := d.client.BusinessRole(businessID)
+			if role == "" {
+				role = "unknown"
+			}
+			return nil, status.Errorf(
+				codes.PermissionDenied,
+				"the FreshBooks identity is not allowed to list the team members of the business %q (%d), its role there is %s and must be owner or admin: %v",
+				business.Name, business.ID, role, err,
+			)
+		case err != nil:
+			return nil, fmt.Errorf("error listing the team members of the business %q (%d): %w", business.Name, business.ID, err)
+		}
+	}
+
 	return nil, nil
 }
 
 // New returns a new instance of the connector.
-func New(_ context.Context, opts ...Option) (*Connector, error) {
-	connector := &Connector{}
+func New(ctx context.Context, opts ...Option) (*Connector, error) {
+	connector := &Connector{
+		defaultRole:        DefaultRoleName,
+		teamMemberCacheTTL: DefaultTeamMemberCacheTTL,
+	}
 	for _, opt := range opts {
 		err := opt(connector)
 		if err != nil {
@@ -83,5 +291,22 @@ func New(_ context.Context, opts ...Option) (*Connector, error) {
 		}
 	}
 
+	fbc, err := client.New(ctx, connector.clientOpts...)
+	if err != nil {
+		return nil, fmt.Errorf("error creating FreshBooks client: %w", err)
+	}
+	connector.client = fbc
+	connector.teamMembers = newTeamMemberCache(fbc, connector.teamMemberCacheTTL, connector.teamMemberState, connector.webhookQueue)
+	connector.clients = newClientCache(fbc, listedResourceTTL)
+
+	// The event feed keeps the roles it has seen in the same state, or in memory when there is no file to keep them in.
+	connector.eventState = connector.teamMemberState
+	if connector.eventState == nil {
+		connector.eventState, err = newTeamMemberState("", "")
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	return connector, nil
 }
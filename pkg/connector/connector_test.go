@@ -0,0 +1,833 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+	"github.com/conductorone/baton-freshbooks/pkg/client/fake"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/oauth2"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const fakeBusinessID = 4521187
+
+// newFakeServer starts a fake FreshBooks API with a business and its team members.
+func newFakeServer(t *testing.T) *fake.Server {
+	server := fake.NewServer()
+	t.Cleanup(server.Close)
+
+	server.AddBusiness(client.Business{ID: fakeBusinessID, BusinessUUID: "business-uuid", Name: "Acme", AccountID: "xZNQ1X"})
+	server.AddTeamMembers(fakeBusinessID,
+		client.TeamMember{UUID: "owner", Email: "owner@example.com", BusinessRoleName: ownerRoleName, Active: true, IdentityID: fake.IdentityID},
+		client.TeamMember{UUID: "manager", Email: "manager@example.com", BusinessRoleName: "business_manager", Active: true},
+		client.TeamMember{UUID: "employee", Email: "employee@example.com", BusinessRoleName: "business_employee", Active: true},
+		client.TeamMember{UUID: "contractor", Email: "contractor@example.com", BusinessRoleName: "contractor", Active: true},
+		client.TeamMember{UUID: "former", Email: "former@example.com", BusinessRoleName: "contractor", Active: false},
+	)
+
+	return server
+}
+
+func newFakeClient(t *testing.T, server *fake.Server) *client.FreshBooksClient {
+	c, err := client.New(
+		context.Background(),
+		client.WithBaseURL(server.URL),
+		client.WithBearerToken(fake.AccessToken),
+		client.WithRetryConfig(client.RetryConfig{MaxRetries: 2, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond, Budget: time.Second}),
+	)
+	require.NoError(t, err)
+
+	return c
+}
+
+func newTestUserBuilder(c *client.FreshBooksClient) *userBuilder {
+	return newUserBuilder(c, newTeamMemberCache(c, DefaultTeamMemberCacheTTL, nil, nil), DefaultRoleName, accountClassifier{})
+}
+
+func newTestRoleBuilder(c *client.FreshBooksClient) *roleBuilder {
+	return newRoleBuilder(c, newTeamMemberCache(c, DefaultTeamMemberCacheTTL, nil, nil), DefaultRoleName, false)
+}
+
+func fakeBusinessResourceID() *v2.ResourceId {
+	return &v2.ResourceId{ResourceType: businessResourceType.Id, Resource: "4521187"}
+}
+
+func TestUserBuilderListPaginates(t *testing.T) {
+	server := newFakeServer(t)
+	u := newTestUserBuilder(newFakeClient(t, server))
+
+	var (
+		ids   []string
+		token = &pagination.Token{Size: 2}
+		pages int
+	)
+	for {
+		users, nextToken, _, err := u.List(context.Background(), fakeBusinessResourceID(), token)
+		require.NoError(t, err)
+
+		for _, user := range users {
+			ids = append(ids, user.Id.Resource)
+			assert.Equal(t, fakeBusinessResourceID().Resource, user.ParentResourceId.Resource)
+		}
+
+		pages++
+		if nextToken == "" {
+			break
+		}
+		token = &pagination.Token{Size: 2, Token: nextToken}
+	}
+
+	// The last page is the one of the legacy staff, the fake account has none.
+	assert.Equal(t, 4, pages)
+	assert.Equal(t, []string{"owner", "manager", "employee", "contractor", "former"}, ids)
+}
+
+func TestUserBuilderListsLegacyStaff(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	server.AddStaff("xZNQ1X",
+		client.Staff{ID: 1, Email: "Manager@example.com", FirstName: "Same", LastName: "Email"},
+		client.Staff{ID: 2, Email: "owner-legacy@example.com", IdentityID: fake.IdentityID},
+		client.Staff{ID: 3, Email: "bookkeeper@example.com", FirstName: "Legacy", LastName: "Bookkeeper", LastLogin: "2023-04-05 06:07:08"},
+		client.Staff{ID: 4, Email: "gone@example.com", VisState: 1},
+	)
+	u := newTestUserBuilder(newFakeClient(t, server))
+
+	var (
+		users []*v2.Resource
+		token = &pagination.Token{Size: 2}
+	)
+	for {
+		page, nextToken, _, err := u.List(ctx, fakeBusinessResourceID(), token)
+		require.NoError(t, err)
+		users = append(users, page...)
+
+		if nextToken == "" {
+			break
+		}
+		token = &pagination.Token{Size: 2, Token: nextToken}
+	}
+
+	require.Len(t, users, 7)
+
+	owner := findResource(t, users, "owner")
+	ownerTrait, err := rs.GetUserTrait(owner)
+	require.NoError(t, err)
+	source, _ := rs.GetProfileStringValue(ownerTrait.Profile, "source")
+	assert.Equal(t, userSourceTeamMember, source)
+
+	bookkeeper := findResource(t, users, staffUserResourceID("xZNQ1X", 3))
+	assert.Equal(t, "Legacy Bookkeeper", bookkeeper.DisplayName)
+	bookkeeperTrait, err := rs.GetUserTrait(bookkeeper)
+	require.NoError(t, err)
+	source, _ = rs.GetProfileStringValue(bookkeeperTrait.Profile, "source")
+	assert.Equal(t, userSourceStaff, source)
+	assert.Equal(t, v2.UserTrait_Status_STATUS_ENABLED, bookkeeperTrait.Status.Status)
+	assert.NotNil(t, bookkeeperTrait.LastLogin)
+
+	gone := findResource(t, users, staffUserResourceID("xZNQ1X", 4))
+	goneTrait, err := rs.GetUserTrait(gone)
+	require.NoError(t, err)
+	assert.Equal(t, v2.UserTrait_Status_STATUS_DISABLED, goneTrait.Status.Status)
+
+	_, err = u.Delete(ctx, bookkeeper.Id)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestUserBuilderListRetriesRateLimitedRequests(t *testing.T) {
+	server := newFakeServer(t)
+	server.FailNext(http.StatusTooManyRequests, "Too many requests")
+	u := newTestUserBuilder(newFakeClient(t, server))
+
+	users, _, _, err := u.List(context.Background(), fakeBusinessResourceID(), &pagination.Token{Size: 50})
+	require.NoError(t, err)
+	assert.Len(t, users, 5)
+}
+
+func TestUserBuilderListRejectedCredentials(t *testing.T) {
+	server := newFakeServer(t)
+	server.RevokeAccessTokens()
+	u := newTestUserBuilder(newFakeClient(t, server))
+
+	_, _, _, err := u.List(context.Background(), fakeBusinessResourceID(), &pagination.Token{Size: 50})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestRoleBuilderGrants(t *testing.T) {
+	server := newFakeServer(t)
+	r := newTestRoleBuilder(newFakeClient(t, server))
+
+	roles, _, _, err := r.List(context.Background(), fakeBusinessResourceID(), &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, roles, len(availableRoles))
+
+	contractorRole := findResource(t, roles, roleResourceID("4521187", "contractor"))
+	grants, _, _, err := r.Grants(context.Background(), contractorRole, &pagination.Token{})
+	require.NoError(t, err)
+
+	var principals []string
+	for _, g := range userGrants(grants) {
+		principals = append(principals, g.Principal.Id.Resource)
+	}
+	// The deactivated team member keeps its role, but isn't granted it.
+	assert.ElementsMatch(t, []string{"contractor"}, principals)
+}
+
+func TestRoleBuilderListsObservedRoles(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	server.AddTeamMembers(fakeBusinessID,
+		client.TeamMember{UUID: "payroll", Email: "payroll@example.com", BusinessRoleName: "payroll_admin", Active: true},
+	)
+	r := newTestRoleBuilder(newFakeClient(t, server))
+
+	roles, _, _, err := r.List(ctx, fakeBusinessResourceID(), &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, roles, len(availableRoles)+1)
+
+	payrollRole := findResource(t, roles, roleResourceID("4521187", "payroll_admin"))
+	assert.Equal(t, "payroll_admin", payrollRole.DisplayName)
+
+	roleTrait, err := rs.GetRoleTrait(payrollRole)
+	require.NoError(t, err)
+	id, ok := rs.GetProfileStringValue(roleTrait.GetProfile(), "id")
+	require.True(t, ok)
+	assert.Equal(t, "payroll_admin", id)
+	assert.False(t, roleTrait.GetProfile().GetFields()["known"].GetBoolValue())
+
+	grants, _, _, err := r.Grants(ctx, payrollRole, &pagination.Token{})
+	require.NoError(t, err)
+	// A role that isn't known has no permissions, so the only grant is the one of its team member.
+	require.Len(t, grants, 1)
+	assert.Equal(t, "payroll", grants[0].Principal.Id.Resource)
+}
+
+func TestRoleBuilderGrantAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	r := newTestRoleBuilder(newFakeClient(t, server))
+
+	roles, _, _, err := r.List(ctx, fakeBusinessResourceID(), &pagination.Token{})
+	require.NoError(t, err)
+	managerRole := findResource(t, roles, roleResourceID("4521187", "business_manager"))
+
+	entitlements, _, _, err := r.Entitlements(ctx, managerRole, &pagination.Token{})
+	require.NoError(t, err)
+
+	principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "contractor"}}
+
+	grants, _, err := r.Grant(ctx, principal, findEntitlement(t, entitlements, permissionName))
+	require.NoError(t, err)
+	require.Len(t, grants, 1)
+
+	teamMember, ok := server.TeamMember(fakeBusinessID, "contractor")
+	require.True(t, ok)
+	assert.Equal(t, "business_manager", teamMember.BusinessRoleName)
+
+	annos, err := r.Revoke(ctx, grant.NewGrant(managerRole, permissionName, principal.Id))
+	require.NoError(t, err)
+	assert.False(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+
+	teamMember, ok = server.TeamMember(fakeBusinessID, "contractor")
+	require.True(t, ok)
+	assert.Equal(t, DefaultRoleName, teamMember.BusinessRoleName)
+
+	annos, err = r.Revoke(ctx, grant.NewGrant(managerRole, permissionName, principal.Id))
+	require.NoError(t, err)
+	assert.True(t, annos.Contains(&v2.GrantAlreadyRevoked{}))
+}
+
+func TestRoleBuilderPermissions(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	r := newTestRoleBuilder(newFakeClient(t, server))
+	r.rolePermissions = true
+
+	roles, _, _, err := r.List(ctx, fakeBusinessResourceID(), &pagination.Token{})
+	require.NoError(t, err)
+	accountantRole := findResource(t, roles, roleResourceID("4521187", "no_seat_employee"))
+
+	entitlements, _, _, err := r.Entitlements(ctx, accountantRole, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, entitlements, 1+len(rolePermissionMatrix["no_seat_employee"]))
+	bankConnections := findEntitlement(t, entitlements, "manage_bank_connections")
+	assert.Equal(t, roleResourceType.Id, bankConnections.GrantableTo[0].Id)
+	assert.Contains(t, bankConnections.Description, "approximate")
+
+	grants, _, _, err := r.Grants(ctx, accountantRole, &pagination.Token{})
+	require.NoError(t, err)
+
+	var permissionGrant *v2.Grant
+	for _, g := range grants {
+		if g.Entitlement.Id == bankConnections.Id {
+			permissionGrant = g
+		}
+	}
+	require.NotNil(t, permissionGrant)
+	assert.Equal(t, accountantRole.Id.Resource, permissionGrant.Principal.Id.Resource)
+
+	expandable := &v2.GrantExpandable{}
+	annos := annotations.Annotations(permissionGrant.Annotations)
+	ok, err := annos.Pick(expandable)
+	require.NoError(t, err)
+	require.True(t, ok)
+	assert.Equal(t, []string{findEntitlement(t, entitlements, permissionName).Id}, expandable.EntitlementIds)
+
+	principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "employee"}}
+	_, _, err = r.Grant(ctx, principal, bankConnections)
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestRoleBuilderPermissionsDisabled(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	r := newTestRoleBuilder(newFakeClient(t, server))
+
+	roles, _, _, err := r.List(ctx, fakeBusinessResourceID(), &pagination.Token{})
+	require.NoError(t, err)
+	accountantRole := findResource(t, roles, roleResourceID("4521187", "no_seat_employee"))
+
+	entitlements, _, _, err := r.Entitlements(ctx, accountantRole, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, entitlements, 1)
+	assert.Equal(t, permissionName, entitlementSlug(entitlements[0]))
+
+	grants, _, _, err := r.Grants(ctx, accountantRole, &pagination.Token{})
+	require.NoError(t, err)
+	assert.Len(t, userGrants(grants), len(grants))
+}
+
+func TestRoleBuilderRefusesOwner(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	r := newTestRoleBuilder(newFakeClient(t, server))
+
+	roles, _, _, err := r.List(ctx, fakeBusinessResourceID(), &pagination.Token{})
+	require.NoError(t, err)
+	ownerRole := findResource(t, roles, roleResourceID("4521187", ownerRoleName))
+	managerRole := findResource(t, roles, roleResourceID("4521187", "business_manager"))
+
+	owner := &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "owner"}
+
+	_, err = r.Revoke(ctx, grant.NewGrant(ownerRole, permissionName, owner))
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	entitlements, _, _, err := r.Entitlements(ctx, managerRole, &pagination.Token{})
+	require.NoError(t, err)
+	_, _, err = r.Grant(ctx, &v2.Resource{Id: owner}, findEntitlement(t, entitlements, permissionName))
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	teamMember, ok := server.TeamMember(fakeBusinessID, "owner")
+	require.True(t, ok)
+	assert.Equal(t, ownerRoleName, teamMember.BusinessRoleName)
+}
+
+func TestRoleBuilderRefusesDeactivatedTeamMembers(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	r := newTestRoleBuilder(newFakeClient(t, server))
+
+	roles, _, _, err := r.List(ctx, fakeBusinessResourceID(), &pagination.Token{})
+	require.NoError(t, err)
+	managerRole := findResource(t, roles, roleResourceID("4521187", "business_manager"))
+	entitlements, _, _, err := r.Entitlements(ctx, managerRole, &pagination.Token{})
+	require.NoError(t, err)
+
+	former := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "former"}}
+	_, _, err = r.Grant(ctx, former, findEntitlement(t, entitlements, permissionName))
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+
+	teamMember, ok := server.TeamMember(fakeBusinessID, "former")
+	require.True(t, ok)
+	assert.Equal(t, "contractor", teamMember.BusinessRoleName)
+}
+
+// teamMemberListRequests counts the requests listing the team members of a business.
+func teamMemberListRequests(server *fake.Server) int {
+	var count int
+	for _, request := range server.Requests() {
+		if strings.HasPrefix(request, http.MethodGet+" ") && strings.HasSuffix(request, "/team_members") {
+			count++
+		}
+	}
+
+	return count
+}
+
+func TestTeamMemberCacheSharedBetweenBuilders(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	c := newFakeClient(t, server)
+	cache := newTeamMemberCache(c, DefaultTeamMemberCacheTTL, nil, nil)
+	u := newUserBuilder(c, cache, DefaultRoleName, accountClassifier{})
+	r := newRoleBuilder(c, cache, DefaultRoleName, false)
+
+	token := &pagination.Token{Size: 2}
+	for {
+		_, nextToken, _, err := u.List(ctx, fakeBusinessResourceID(), token)
+		require.NoError(t, err)
+		if nextToken == "" {
+			break
+		}
+		token = &pagination.Token{Size: 2, Token: nextToken}
+	}
+
+	roles, _, _, err := r.List(ctx, fakeBusinessResourceID(), &pagination.Token{})
+	require.NoError(t, err)
+	for _, role := range roles {
+		_, _, _, err := r.Grants(ctx, role, &pagination.Token{})
+		require.NoError(t, err)
+	}
+
+	assert.Equal(t, 1, teamMemberListRequests(server))
+}
+
+func TestTeamMemberCacheExpires(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	cache := newTeamMemberCache(newFakeClient(t, server), time.Minute, nil, nil)
+
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	_, _, err := cache.Get(ctx, fakeBusinessResourceID().Resource)
+	require.NoError(t, err)
+	_, _, err = cache.Get(ctx, fakeBusinessResourceID().Resource)
+	require.NoError(t, err)
+	assert.Equal(t, 1, teamMemberListRequests(server))
+
+	now = now.Add(time.Minute)
+	_, _, err = cache.Get(ctx, fakeBusinessResourceID().Resource)
+	require.NoError(t, err)
+	assert.Equal(t, 2, teamMemberListRequests(server))
+}
+
+// lastTeamMemberListQuery returns the query string of the last request listing the team members.
+func lastTeamMemberListQuery(t *testing.T, server *fake.Server) string {
+	requests, queries := server.Requests(), server.Queries()
+	for i := len(requests) - 1; i >= 0; i-- {
+		if strings.HasPrefix(requests[i], http.MethodGet+" ") && strings.HasSuffix(requests[i], "/team_members") {
+			return queries[i]
+		}
+	}
+
+	require.Fail(t, "no team member was listed")
+	return ""
+}
+
+func TestTeamMemberStateRequestsChangedTeamMembers(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	server.AddTeamMembers(fakeBusinessID, client.TeamMember{UUID: "veteran", BusinessRoleName: "business_employee", Active: true, CreatedAt: "2024-01-01T00:00:00Z"})
+	c := newFakeClient(t, server)
+	businessID := fakeBusinessResourceID().Resource
+	path := filepath.Join(t.TempDir(), "team-members.json")
+
+	state, err := newTeamMemberState(path, "passphrase")
+	require.NoError(t, err)
+	_, _, err = newTeamMemberCache(c, DefaultTeamMemberCacheTTL, state, nil).Get(ctx, businessID)
+	require.NoError(t, err)
+	assert.NotContains(t, lastTeamMemberListQuery(t, server), "updated_since")
+
+	_, _, err = c.UpdateTeamMember(ctx, businessID, "contractor", client.TeamMemberUpdate{BusinessRoleName: "business_manager"})
+	require.NoError(t, err)
+
+	// The next sync loads the state written by the previous one.
+	state, err = newTeamMemberState(path, "passphrase")
+	require.NoError(t, err)
+	cache := newTeamMemberCache(c, DefaultTeamMemberCacheTTL, state, nil)
+	teamMembers, _, err := cache.Get(ctx, businessID)
+	require.NoError(t, err)
+	assert.Contains(t, lastTeamMemberListQuery(t, server), "updated_since=2024-01-01T00%3A00%3A00Z")
+	require.Len(t, teamMembers, 6)
+	for _, teamMember := range teamMembers {
+		if teamMember.UUID == "contractor" {
+			assert.Equal(t, "business_manager", teamMember.BusinessRoleName)
+		}
+	}
+
+	now := time.Now().Add(teamMemberFullRefreshInterval)
+	cache.now = func() time.Time { return now }
+	cache.Invalidate(businessID)
+	teamMembers, _, err = cache.Get(ctx, businessID)
+	require.NoError(t, err)
+	assert.NotContains(t, lastTeamMemberListQuery(t, server), "updated_since")
+	assert.Len(t, teamMembers, 6)
+}
+
+func TestTeamMemberStateIsEncrypted(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	c := newFakeClient(t, server)
+	path := filepath.Join(t.TempDir(), "team-members.json")
+
+	state, err := newTeamMemberState(path, "passphrase")
+	require.NoError(t, err)
+	_, _, err = newTeamMemberCache(c, DefaultTeamMemberCacheTTL, state, nil).Get(ctx, fakeBusinessResourceID().Resource)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "owner@example.com")
+
+	_, err = newTeamMemberState(path, "wrong passphrase")
+	assert.Error(t, err)
+}
+
+func TestTeamMemberStateFallsBackWhenUpdatedSinceIsIgnored(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	server.AddTeamMembers(fakeBusinessID, client.TeamMember{UUID: "veteran", BusinessRoleName: "business_employee", Active: true, CreatedAt: "2024-01-01T00:00:00Z"})
+	c := newFakeClient(t, server)
+	businessID := fakeBusinessResourceID().Resource
+	path := filepath.Join(t.TempDir(), "team-members.json")
+
+	state, err := newTeamMemberState(path, "passphrase")
+	require.NoError(t, err)
+	_, _, err = newTeamMemberCache(c, DefaultTeamMemberCacheTTL, state, nil).Get(ctx, businessID)
+	require.NoError(t, err)
+	previous, ok := state.Load(businessID)
+	require.True(t, ok)
+
+	server.IgnoreUpdatedSince()
+	state, err = newTeamMemberState(path, "passphrase")
+	require.NoError(t, err)
+	cache := newTeamMemberCache(c, DefaultTeamMemberCacheTTL, state, nil)
+	now := previous.FullFetchedAt.Add(time.Hour)
+	cache.now = func() time.Time { return now }
+	teamMembers, _, err := cache.Get(ctx, businessID)
+	require.NoError(t, err)
+	assert.Contains(t, lastTeamMemberListQuery(t, server), "updated_since")
+	assert.Len(t, teamMembers, 6)
+
+	// The answer had every team member, so it counts as a full request.
+	snapshot, ok := state.Load(businessID)
+	require.True(t, ok)
+	assert.True(t, now.Equal(snapshot.FullFetchedAt))
+}
+
+func TestIsFullTeamMemberList(t *testing.T) {
+	previous := teamMemberSnapshot{
+		TeamMembers: []client.TeamMember{
+			{UUID: "kept", UpdatedAt: "2024-01-01T00:00:00Z"},
+			{UUID: "left", UpdatedAt: "2024-01-01T00:00:00Z"},
+		},
+		HighWaterMark: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	tests := []struct {
+		name     string
+		changed  []client.TeamMember
+		expected bool
+	}{
+		{name: "nothing changed", expected: false},
+		{
+			name:     "ignored filter",
+			changed:  []client.TeamMember{{UUID: "kept", UpdatedAt: "2023-06-01T00:00:00Z"}},
+			expected: true,
+		},
+		{
+			name: "every team member changed",
+			changed: []client.TeamMember{
+				{UUID: "kept", UpdatedAt: "2024-02-01T00:00:00Z"},
+				{UUID: "left", UpdatedAt: "2024-02-01T00:00:00Z"},
+			},
+			expected: true,
+		},
+		{
+			// As many team members as before, but the unchanged one isn't in the answer.
+			name: "new team members",
+			changed: []client.TeamMember{
+				{UUID: "left", UpdatedAt: "2024-02-01T00:00:00Z"},
+				{UUID: "joined", CreatedAt: "2024-02-01T00:00:00Z"},
+			},
+			expected: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, isFullTeamMemberList(tt.changed, previous))
+		})
+	}
+}
+
+func TestTeamMemberCacheInvalidatedAfterGrant(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	r := newTestRoleBuilder(newFakeClient(t, server))
+
+	roles, _, _, err := r.List(ctx, fakeBusinessResourceID(), &pagination.Token{})
+	require.NoError(t, err)
+	managerRole := findResource(t, roles, roleResourceID("4521187", "business_manager"))
+
+	grants, _, _, err := r.Grants(ctx, managerRole, &pagination.Token{})
+	require.NoError(t, err)
+	require.Len(t, userGrants(grants), 1)
+
+	entitlements, _, _, err := r.Entitlements(ctx, managerRole, &pagination.Token{})
+	require.NoError(t, err)
+	principal := &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "employee"}}
+	_, _, err = r.Grant(ctx, principal, findEntitlement(t, entitlements, permissionName))
+	require.NoError(t, err)
+
+	grants, _, _, err = r.Grants(ctx, managerRole, &pagination.Token{})
+	require.NoError(t, err)
+	assert.Len(t, userGrants(grants), 2)
+}
+
+func TestUserBuilderDelete(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	u := newTestUserBuilder(newFakeClient(t, server))
+
+	_, err := u.Delete(ctx, &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "employee"})
+	require.NoError(t, err)
+
+	teamMember, ok := server.TeamMember(fakeBusinessID, "employee")
+	require.True(t, ok)
+	assert.False(t, teamMember.Active)
+
+	_, err = u.Delete(ctx, &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "former"})
+	assert.NoError(t, err)
+
+	_, err = u.Delete(ctx, &v2.ResourceId{ResourceType: userResourceType.Id, Resource: "owner"})
+	assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+}
+
+func TestUserBuilderCreateAccount(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	u := newTestUserBuilder(newFakeClient(t, server))
+
+	profile, err := structpb.NewStruct(map[string]interface{}{
+		"email":      "new@example.com",
+		"first_name": "New",
+		"last_name":  "Hire",
+		"role":       "no_seat_employee",
+	})
+	require.NoError(t, err)
+
+	res, _, _, err := u.CreateAccount(ctx, &v2.AccountInfo{Profile: profile}, nil)
+	require.NoError(t, err)
+
+	result, ok := res.(*v2.CreateAccountResponse_SuccessResult)
+	require.True(t, ok)
+	assert.Equal(t, "New Hire", result.Resource.DisplayName)
+
+	userTrait, err := rs.GetUserTrait(result.Resource)
+	require.NoError(t, err)
+	assert.Equal(t, userStatusPending, userTrait.GetStatus().GetDetails())
+}
+
+func TestUserBuilderCreate(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	u := newTestUserBuilder(newFakeClient(t, server))
+
+	resource, err := rs.NewUserResource(
+		"New Hire",
+		userResourceType,
+		"new@example.com",
+		[]rs.UserTraitOption{
+			rs.WithUserProfile(map[string]interface{}{"first_name": "New", "last_name": "Hire"}),
+			rs.WithEmail("new@example.com", true),
+		},
+		rs.WithParentResourceID(fakeBusinessResourceID()),
+	)
+	require.NoError(t, err)
+
+	created, _, err := u.Create(ctx, resource)
+	require.NoError(t, err)
+	assert.Equal(t, "New Hire", created.DisplayName)
+	assert.Equal(t, fakeBusinessResourceID().Resource, created.ParentResourceId.Resource)
+
+	userTrait, err := rs.GetUserTrait(created)
+	require.NoError(t, err)
+	assert.Equal(t, userStatusPending, userTrait.GetStatus().GetDetails())
+
+	teamMember, ok := server.TeamMember(fakeBusinessID, userTrait.GetProfile().GetFields()["uuid"].GetStringValue())
+	require.True(t, ok)
+	assert.Equal(t, DefaultRoleName, teamMember.BusinessRoleName)
+}
+
+// memoryTokenStore keeps the token in memory, to check what the client persists.
+type memoryTokenStore struct {
+	token *oauth2.Token
+}
+
+func (m *memoryTokenStore) Load(_ context.Context) (*oauth2.Token, error) {
+	return m.token, nil
+}
+
+func (m *memoryTokenStore) Save(_ context.Context, token *oauth2.Token) error {
+	m.token = token
+	return nil
+}
+
+func TestRefreshTokenRotation(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	store := &memoryTokenStore{}
+
+	c, err := client.New(
+		ctx,
+		client.WithBaseURL(server.URL),
+		client.WithRefreshToken(ctx, fake.RefreshToken, fake.ClientID, fake.ClientSecret),
+		client.WithTokenStore(store),
+	)
+	require.NoError(t, err)
+
+	users, _, _, err := newTestUserBuilder(c).List(ctx, fakeBusinessResourceID(), &pagination.Token{Size: 50})
+	require.NoError(t, err)
+	assert.Len(t, users, 5)
+	assert.Equal(t, 1, server.TokenRequests())
+
+	require.NotNil(t, store.token)
+	assert.Equal(t, server.CurrentRefreshToken(), store.token.RefreshToken)
+
+	// The next run starts from the stored token, since the configured refresh token was already exchanged.
+	c, err = client.New(
+		ctx,
+		client.WithBaseURL(server.URL),
+		client.WithRefreshToken(ctx, fake.RefreshToken, fake.ClientID, fake.ClientSecret),
+		client.WithTokenStore(store),
+	)
+	require.NoError(t, err)
+
+	connector := &Connector{client: c}
+	_, err = connector.Validate(ctx)
+	require.NoError(t, err)
+}
+
+func TestValidateRejectedRefreshToken(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+
+	c, err := client.New(
+		ctx,
+		client.WithBaseURL(server.URL),
+		client.WithRefreshToken(ctx, "revoked-refresh-token", fake.ClientID, fake.ClientSecret),
+	)
+	require.NoError(t, err)
+
+	connector := &Connector{client: c}
+	_, err = connector.Validate(ctx)
+	require.Error(t, err)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
+
+func TestValidateListsTheTeamMembers(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	connector := &Connector{client: newFakeClient(t, server)}
+
+	_, err := connector.Validate(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, teamMemberListRequests(server))
+
+	server.SetBusinessRole(fakeBusinessID, "business_employee")
+	c := newFakeClient(t, server)
+	require.NoError(t, c.EnsureBusinesses(ctx))
+	server.FailNext(http.StatusForbidden, "The identity is not allowed to list the team members")
+	connector = &Connector{client: c}
+
+	// The role only explains the refusal of FreshBooks.
+	_, err = connector.Validate(ctx)
+	require.Error(t, err)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+	assert.Contains(t, err.Error(), "business_employee")
+}
+
+// userGrants returns the grants of a role to users, leaving out the permissions granted to the role itself.
+func userGrants(grants []*v2.Grant) []*v2.Grant {
+	var ret []*v2.Grant
+	for _, g := range grants {
+		if g.Principal.Id.ResourceType == userResourceType.Id {
+			ret = append(ret, g)
+		}
+	}
+
+	return ret
+}
+
+func findEntitlement(t *testing.T, entitlements []*v2.Entitlement, slug string) *v2.Entitlement {
+	for _, ent := range entitlements {
+		if ent.Slug == slug {
+			return ent
+		}
+	}
+
+	t.Fatalf("entitlement %s not found", slug)
+	return nil
+}
+
+func findResource(t *testing.T, resources []*v2.Resource, id string) *v2.Resource {
+	for _, resource := range resources {
+		if resource.Id.Resource == id {
+			return resource
+		}
+	}
+
+	t.Fatalf("resource %s not found", id)
+	return nil
+}
+
+func TestClientBuildersShareTheClients(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	server.AddClients("xZNQ1X",
+		client.Client{ID: 1, Organization: "Globex", Email: "hank@globex.com", Contacts: []client.ClientContact{{ID: 10, Email: "frank@globex.com"}}},
+		client.Client{ID: 2, Organization: "Initech", Email: "bill@initech.com"},
+	)
+	c := newFakeClient(t, server)
+	clients := newClientCache(c, listedResourceTTL)
+	clientBuilder := newClientBuilder(c, clients)
+	contactBuilder := newClientContactBuilder(c, clients)
+
+	var clientResources []*v2.Resource
+	token := &pagination.Token{Size: 1}
+	for {
+		page, nextToken, _, err := clientBuilder.List(ctx, fakeBusinessResourceID(), token)
+		require.NoError(t, err)
+		clientResources = append(clientResources, page...)
+		if nextToken == "" {
+			break
+		}
+		token = &pagination.Token{Size: 1, Token: nextToken}
+	}
+	require.Len(t, clientResources, 2)
+
+	contacts, nextToken, _, err := contactBuilder.List(ctx, fakeBusinessResourceID(), &pagination.Token{Size: 50})
+	require.NoError(t, err)
+	assert.Empty(t, nextToken)
+	assert.Len(t, contacts, 3)
+
+	grants, _, _, err := clientBuilder.Grants(ctx, findResource(t, clientResources, "1"), &pagination.Token{})
+	require.NoError(t, err)
+	assert.Len(t, grants, 2)
+
+	// The clients, the client contacts and the grants come from a single request.
+	var clientRequests int
+	for _, request := range server.Requests() {
+		if strings.HasSuffix(request, "/users/clients") {
+			clientRequests++
+		}
+	}
+	assert.Equal(t, 1, clientRequests)
+}
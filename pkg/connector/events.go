@@ -0,0 +1,225 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"time"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/types/known/timestamppb"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+)
+
+var _ connectorbuilder.EventProvider = (*Connector)(nil)
+
+// eventCursor is the state kept between two calls to ListEvents, in the cursor of the stream. The roles of the
+// team members seen on the last call are kept on the connector side, in its team member state, so the cursor
+// doesn't grow with the number of team members.
+type eventCursor struct {
+	Since time.Time `json:"since"`
+}
+
+// ListEvents returns the changes of the team members since the last call: a usage event when a team member accepts
+// its invitation, and grant and revoke events when a team member joins, changes role, is deactivated or leaves.
+// The events come from comparing the team members with the roles seen on the last call, so the first call without
+// a cursor, or a call whose roles weren't kept, only reports the team members created or updated since the cursor.
+// The team members of every business are requested on each call, since FreshBooks has no webhook for their changes.
+func (d *Connector) ListEvents(
+	ctx context.Context,
+	earliestEvent *timestamppb.Timestamp,
+	pToken *pagination.StreamToken,
+) ([]*v2.Event, *pagination.StreamState, annotations.Annotations, error) {
+	cursor, resumed, err := parseEventCursor(pToken, earliestEvent)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	err = d.client.EnsureBusinesses(ctx)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	next := eventCursor{Since: cursor.Since}
+	baselines := make(map[string]eventBaseline)
+
+	var (
+		events     []*v2.Event
+		annotation annotations.Annotations
+	)
+	for _, business := range d.client.Businesses() {
+		businessID := strconv.FormatInt(business.ID, 10)
+
+		var teamMembers []client.TeamMember
+		teamMembers, annotation, err = d.teamMembers.Refresh(ctx, businessID)
+		if err != nil {
+			return nil, nil, annotation, err
+		}
+
+		// The roles kept by the last call are only compared with when they were kept for this cursor.
+		previous, ok := d.eventState.LoadEventBaseline(businessID)
+		hasBaseline := resumed && ok && previous.Since.Equal(cursor.Since)
+
+		roles := make(map[string]string)
+		seen := make(map[string]bool, len(teamMembers))
+		for _, teamMember := range teamMembers {
+			seen[teamMember.UUID] = true
+
+			role := ""
+			if teamMember.Active {
+				role = teamMember.BusinessRoleName
+				roles[teamMember.UUID] = role
+			}
+
+			updatedAt, _ := parseTimestamp(teamMember.UpdatedAt)
+			if updatedAt.After(next.Since) {
+				next.Since = updatedAt
+			}
+
+			if acceptedAt, ok := parseTimestamp(teamMember.InvitationDateAccepted); ok && acceptedAt.After(cursor.Since) {
+				events = append(events, newUsageEvent(businessID, teamMember, acceptedAt))
+				if acceptedAt.After(next.Since) {
+					next.Since = acceptedAt
+				}
+			}
+
+			occurredAt := updatedAt
+			if occurredAt.IsZero() {
+				occurredAt = time.Now()
+			}
+
+			if !hasBaseline {
+				// Without the roles of the last call, a team member created or updated since then is reported
+				// with the role it has now.
+				createdAt, _ := parseTimestamp(teamMember.CreatedAt)
+				if role != "" && (createdAt.After(cursor.Since) || updatedAt.After(cursor.Since)) {
+					events = append(events, newGrantEvent(businessID, teamMember.UUID, role, occurredAt))
+				}
+				continue
+			}
+
+			previousRole := previous.Roles[teamMember.UUID]
+			if previousRole == role {
+				continue
+			}
+			if previousRole != "" {
+				events = append(events, newRevokeEvent(businessID, teamMember.UUID, previousRole, occurredAt))
+			}
+			if role != "" {
+				events = append(events, newGrantEvent(businessID, teamMember.UUID, role, occurredAt))
+			}
+		}
+
+		if hasBaseline {
+			// The team members that were removed from the business lose their role too.
+			for teamMemberUUID, previousRole := range previous.Roles {
+				if !seen[teamMemberUUID] {
+					events = append(events, newRevokeEvent(businessID, teamMemberUUID, previousRole, time.Now()))
+				}
+			}
+		}
+
+		baselines[businessID] = eventBaseline{Roles: roles}
+	}
+
+	for businessID, baseline := range baselines {
+		baseline.Since = next.Since
+		baselines[businessID] = baseline
+	}
+
+	// The next call still reports the team members created or updated since the cursor when the roles can't be kept.
+	err = d.eventState.SaveEventBaselines(baselines)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn("error saving the roles seen by the event feed", zap.Error(err))
+	}
+
+	slices.SortStableFunc(events, func(a, b *v2.Event) int {
+		return a.OccurredAt.AsTime().Compare(b.OccurredAt.AsTime())
+	})
+
+	nextCursor, err := json.Marshal(next)
+	if err != nil {
+		return nil, nil, annotation, err
+	}
+
+	return events, &pagination.StreamState{Cursor: string(nextCursor), HasMore: false}, annotation, nil
+}
+
+// parseEventCursor reads the cursor of the stream, and reports whether there was one. Without one, the events
+// start at earliestEvent, or now when it isn't set either, so the first call doesn't report every team member.
+func parseEventCursor(pToken *pagination.StreamToken, earliestEvent *timestamppb.Timestamp) (eventCursor, bool, error) {
+	if pToken == nil || pToken.Cursor == "" {
+		since := time.Now()
+		if earliestEvent != nil {
+			since = earliestEvent.AsTime()
+		}
+
+		return eventCursor{Since: since}, false, nil
+	}
+
+	var cursor eventCursor
+	err := json.Unmarshal([]byte(pToken.Cursor), &cursor)
+	if err != nil {
+		return eventCursor{}, false, fmt.Errorf("baton-freshbooks: invalid event cursor: %w", err)
+	}
+
+	return cursor, true, nil
+}
+
+func newUsageEvent(businessID string, teamMember client.TeamMember, occurredAt time.Time) *v2.Event {
+	return &v2.Event{
+		Id:         fmt.Sprintf("usage:%s:%s:%d", businessID, teamMember.UUID, occurredAt.Unix()),
+		OccurredAt: timestamppb.New(occurredAt),
+		Event: &v2.Event_UsageEvent{
+			UsageEvent: &v2.UsageEvent{
+				TargetResource: &v2.Resource{Id: &v2.ResourceId{ResourceType: businessResourceType.Id, Resource: businessID}},
+				ActorResource:  &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: teamMember.UUID}},
+			},
+		},
+	}
+}
+
+func newGrantEvent(businessID, teamMemberUUID, businessRoleName string, occurredAt time.Time) *v2.Event {
+	principal := &v2.ResourceId{ResourceType: userResourceType.Id, Resource: teamMemberUUID}
+
+	return &v2.Event{
+		Id:         fmt.Sprintf("grant:%s:%s:%s:%d", businessID, teamMemberUUID, businessRoleName, occurredAt.Unix()),
+		OccurredAt: timestamppb.New(occurredAt),
+		Event: &v2.Event_GrantEvent{
+			GrantEvent: &v2.GrantEvent{
+				Grant: grant.NewGrant(eventRoleResource(businessID, businessRoleName), permissionName, principal),
+			},
+		},
+	}
+}
+
+func newRevokeEvent(businessID, teamMemberUUID, businessRoleName string, occurredAt time.Time) *v2.Event {
+	return &v2.Event{
+		Id:         fmt.Sprintf("revoke:%s:%s:%s:%d", businessID, teamMemberUUID, businessRoleName, occurredAt.Unix()),
+		OccurredAt: timestamppb.New(occurredAt),
+		Event: &v2.Event_RevokeEvent{
+			RevokeEvent: &v2.RevokeEvent{
+				Entitlement: entitlement.NewPermissionEntitlement(eventRoleResource(businessID, businessRoleName), permissionName),
+				Principal:   &v2.Resource{Id: &v2.ResourceId{ResourceType: userResourceType.Id, Resource: teamMemberUUID}},
+			},
+		},
+	}
+}
+
+// eventRoleResource returns the Role Resource the events refer to, which only needs its ID.
+func eventRoleResource(businessID, businessRoleName string) *v2.Resource {
+	return &v2.Resource{
+		Id:               &v2.ResourceId{ResourceType: roleResourceType.Id, Resource: roleResourceID(businessID, businessRoleName)},
+		ParentResourceId: &v2.ResourceId{ResourceType: businessResourceType.Id, Resource: businessID},
+	}
+}
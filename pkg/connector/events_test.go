@@ -0,0 +1,122 @@
+package connector
+
+import (
+	"context"
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+	"github.com/conductorone/baton-freshbooks/pkg/client/fake"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+func newFakeConnector(t *testing.T, server *fake.Server, opts ...Option) *Connector {
+	opts = append([]Option{WithBaseURL(server.URL), WithAccessToken(context.Background(), fake.AccessToken)}, opts...)
+	connector, err := New(context.Background(), opts...)
+	require.NoError(t, err)
+
+	return connector
+}
+
+// describeEvents summarizes the events as "kind principal role", to compare them regardless of their order.
+func describeEvents(events []*v2.Event) []string {
+	var ret []string
+	for _, event := range events {
+		switch {
+		case event.GetGrantEvent() != nil:
+			g := event.GetGrantEvent().Grant
+			ret = append(ret, "grant "+g.Principal.Id.Resource+" "+g.Entitlement.Resource.Id.Resource)
+		case event.GetRevokeEvent() != nil:
+			r := event.GetRevokeEvent()
+			ret = append(ret, "revoke "+r.Principal.Id.Resource+" "+r.Entitlement.Resource.Id.Resource)
+		case event.GetUsageEvent() != nil:
+			u := event.GetUsageEvent()
+			ret = append(ret, "usage "+u.ActorResource.Id.Resource+" "+u.TargetResource.Id.Resource)
+		}
+	}
+
+	return ret
+}
+
+func TestListEventsReportsTeamMemberChanges(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	connector := newFakeConnector(t, server)
+
+	events, state, _, err := connector.ListEvents(ctx, nil, &pagination.StreamToken{})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+	assert.False(t, state.HasMore)
+
+	_, _, err = connector.client.UpdateTeamMember(ctx, "4521187", "contractor", client.TeamMemberUpdate{BusinessRoleName: "business_manager"})
+	require.NoError(t, err)
+	active := false
+	_, _, err = connector.client.UpdateTeamMember(ctx, "4521187", "employee", client.TeamMemberUpdate{Active: &active})
+	require.NoError(t, err)
+	server.AddTeamMembers(fakeBusinessID, client.TeamMember{
+		UUID:                   "newcomer",
+		Email:                  "newcomer@example.com",
+		BusinessRoleName:       "contractor",
+		Active:                 true,
+		Invited:                true,
+		InvitationDateAccepted: time.Now().Add(time.Minute).UTC().Format(time.RFC3339),
+	})
+
+	events, state, _, err = connector.ListEvents(ctx, nil, &pagination.StreamToken{Cursor: state.Cursor})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"revoke contractor 4521187:contractor",
+		"grant contractor 4521187:business_manager",
+		"revoke employee 4521187:business_employee",
+		"grant newcomer 4521187:contractor",
+		"usage newcomer 4521187",
+	}, describeEvents(events))
+
+	events, _, _, err = connector.ListEvents(ctx, nil, &pagination.StreamToken{Cursor: state.Cursor})
+	require.NoError(t, err)
+	assert.Empty(t, events)
+}
+
+func TestListEventsStartsAtEarliestEvent(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	server.AddTeamMembers(fakeBusinessID,
+		client.TeamMember{UUID: "recent", BusinessRoleName: "business_manager", Active: true, CreatedAt: time.Now().UTC().Format(time.RFC3339)},
+		client.TeamMember{UUID: "old", BusinessRoleName: "business_manager", Active: true, CreatedAt: "2020-01-01T00:00:00Z"},
+	)
+	connector := newFakeConnector(t, server)
+
+	events, _, _, err := connector.ListEvents(ctx, timestamppb.New(time.Now().Add(-time.Hour)), &pagination.StreamToken{})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"grant recent 4521187:business_manager"}, describeEvents(events))
+}
+
+func TestListEventsKeepsRolesInState(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	path := filepath.Join(t.TempDir(), "team-members.json")
+
+	_, state, _, err := newFakeConnector(t, server, WithTeamMemberStatePath(path, "passphrase")).ListEvents(ctx, nil, &pagination.StreamToken{})
+	require.NoError(t, err)
+
+	// The cursor only holds where the events start, whatever the number of team members.
+	var cursor map[string]interface{}
+	require.NoError(t, json.Unmarshal([]byte(state.Cursor), &cursor))
+	assert.Len(t, cursor, 1)
+	assert.Contains(t, cursor, "since")
+
+	active := false
+	_, _, err = newFakeClient(t, server).UpdateTeamMember(ctx, "4521187", "manager", client.TeamMemberUpdate{Active: &active})
+	require.NoError(t, err)
+
+	// A new connector, like the next run of the event feed, compares with the roles kept in the state file.
+	events, _, _, err := newFakeConnector(t, server, WithTeamMemberStatePath(path, "passphrase")).ListEvents(ctx, nil, &pagination.StreamToken{Cursor: state.Cursor})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"revoke manager 4521187:business_manager"}, describeEvents(events))
+}
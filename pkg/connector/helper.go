@@ -1,7 +1,10 @@
 package connector
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
@@ -47,3 +50,50 @@ func unmarshalSkipToken(token *pagination.Token) (int32, *pagination.Bag, error)
 	}
 	return ret, b, nil
 }
+
+// roleResourceID builds the ID of a Role Resource, which is only unique inside its business.
+func roleResourceID(businessID, businessRoleName string) string {
+	return businessID + ":" + businessRoleName
+}
+
+// parseRoleResourceID splits the ID of a Role Resource into the business ID and the business role name.
+func parseRoleResourceID(id string) (string, string, error) {
+	businessID, businessRoleName, found := strings.Cut(id, ":")
+	if !found || businessID == "" || businessRoleName == "" {
+		return "", "", fmt.Errorf("invalid role ID: %s", id)
+	}
+
+	return businessID, businessRoleName, nil
+}
+
+// timestampLayouts are the formats of the dates returned by FreshBooks: the auth API uses RFC 3339,
+// and some endpoints return the date and time without a time zone, which is UTC.
+var timestampLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+}
+
+// parseTimestamp parses a date returned by FreshBooks, reporting whether it could be parsed.
+func parseTimestamp(value string) (time.Time, bool) {
+	if value == "" {
+		return time.Time{}, false
+	}
+
+	for _, layout := range timestampLayouts {
+		t, err := time.Parse(layout, value)
+		if err == nil {
+			return t, true
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// entitlementSlug returns the slug of an entitlement, taking it from the end of its ID when it isn't set.
+func entitlementSlug(ent *v2.Entitlement) string {
+	if ent.Slug != "" {
+		return ent.Slug
+	}
+
+	return ent.Id[strings.LastIndex(ent.Id, ":")+1:]
+}
@@ -13,19 +13,18 @@ import (
 )
 
 var (
-	ctx              = context.Background()
-	message          = ""
-	accessToken, _   = os.LookupEnv("FRESHBOOKS_ACCESS_TOKEN")
-	refreshToken, _  = os.LookupEnv("FRESHBOOKS_REFRESH_TOKEN")
-	clientID, _      = os.LookupEnv("FRESHBOOKS_CLIENT_ID")
-	clientSecret, _  = os.LookupEnv("FRESHBOOKS_CLIENT_SECRET")
-	parentResourceID = &v2.ResourceId{}
-	paginationToken  = &pagination.Token{Size: 50, Token: ""}
+	ctx             = context.Background()
+	message         = ""
+	accessToken, _  = os.LookupEnv("FRESHBOOKS_ACCESS_TOKEN")
+	refreshToken, _ = os.LookupEnv("FRESHBOOKS_REFRESH_TOKEN")
+	clientID, _     = os.LookupEnv("FRESHBOOKS_CLIENT_ID")
+	clientSecret, _ = os.LookupEnv("FRESHBOOKS_CLIENT_SECRET")
+	paginationToken = &pagination.Token{Size: 50, Token: ""}
 )
 
 func TestUserBuilderListWithAcessToken(t *testing.T) {
 	if accessToken == "" {
-		t.Fatal("param token missing")
+		t.Skip("FRESHBOOKS_ACCESS_TOKEN is not set, skipping the integration test")
 	}
 
 	c, err := client.New(
@@ -36,7 +35,8 @@ func TestUserBuilderListWithAcessToken(t *testing.T) {
 		message = fmt.Sprintf("error creating client: %v", err)
 		t.Fatal(message)
 	}
-	u := newUserBuilder(c)
+	parentResourceID := getFirstBusinessID(t, c)
+	u := newTestUserBuilder(c)
 
 	users, _, _, err := u.List(ctx, parentResourceID, paginationToken)
 	assert.Nil(t, err)
@@ -44,8 +44,8 @@ func TestUserBuilderListWithAcessToken(t *testing.T) {
 }
 
 func TestUserBuilderListWithRefreshToken(t *testing.T) {
-	if refreshToken == "" && clientID == "" && clientSecret == "" {
-		t.Fatal("the params refresh-token, fb-client-id and fb-client-secret must be used")
+	if refreshToken == "" || clientID == "" || clientSecret == "" {
+		t.Skip("FRESHBOOKS_REFRESH_TOKEN, FRESHBOOKS_CLIENT_ID and FRESHBOOKS_CLIENT_SECRET are not set, skipping the integration test")
 	}
 
 	c, err := client.New(
@@ -57,8 +57,22 @@ func TestUserBuilderListWithRefreshToken(t *testing.T) {
 		t.Fatal(message)
 	}
 
-	r := newRoleBuilder(c)
+	parentResourceID := getFirstBusinessID(t, c)
+	r := newTestRoleBuilder(c)
 	roles, _, _, err := r.List(ctx, parentResourceID, paginationToken)
 	assert.Nil(t, err)
 	assert.NotNil(t, roles)
 }
+
+func getFirstBusinessID(t *testing.T, c *client.FreshBooksClient) *v2.ResourceId {
+	b := newBusinessBuilder(c)
+	businesses, _, _, err := b.List(ctx, nil, paginationToken)
+	if err != nil {
+		t.Fatalf("error listing businesses: %v", err)
+	}
+	if len(businesses) == 0 {
+		t.Fatal("no businesses found")
+	}
+
+	return businesses[0].Id
+}
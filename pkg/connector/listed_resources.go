@@ -0,0 +1,69 @@
+package connector
+
+import (
+	"sync"
+	"time"
+)
+
+// listedResourceTTL is how long what List requested of a resource is reused by its grants, or by the resources
+// built from it like the client contacts. Past it, the resource is requested again, as it may have changed since.
+const listedResourceTTL = 10 * time.Minute
+
+// listedResources keeps what List requested of each resource until its grants use it, so they don't request it
+// again. An entry is dropped once used, and the ones never used are dropped once they expire, so the cache never
+// holds more than the resources listed and not granted yet.
+type listedResources[T any] struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	entries   map[string]listedResource[T]
+	expiredAt time.Time
+}
+
+// listedResource is a resource, along with when List requested it.
+type listedResource[T any] struct {
+	value    T
+	listedAt time.Time
+}
+
+func newListedResources[T any](ttl time.Duration) *listedResources[T] {
+	return &listedResources[T]{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]listedResource[T]),
+	}
+}
+
+// Put keeps a resource requested by List. The expired entries are dropped at most once per TTL.
+func (l *listedResources[T]) Put(id string, value T) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := l.now()
+	if now.Sub(l.expiredAt) >= l.ttl {
+		for entryID, entry := range l.entries {
+			if now.Sub(entry.listedAt) >= l.ttl {
+				delete(l.entries, entryID)
+			}
+		}
+		l.expiredAt = now
+	}
+
+	l.entries[id] = listedResource[T]{value: value, listedAt: now}
+}
+
+// Take returns a resource kept by Put and drops it, or false when it wasn't kept or has expired.
+func (l *listedResources[T]) Take(id string) (T, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	entry, ok := l.entries[id]
+	delete(l.entries, id)
+	if !ok || l.now().Sub(entry.listedAt) >= l.ttl {
+		var zero T
+		return zero, false
+	}
+
+	return entry.value, true
+}
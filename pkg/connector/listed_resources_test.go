@@ -0,0 +1,34 @@
+package connector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestListedResourcesAreUsedOnce(t *testing.T) {
+	listed := newListedResources[string](time.Minute)
+	now := time.Now()
+	listed.now = func() time.Time { return now }
+
+	listed.Put("granted", "project")
+	value, ok := listed.Take("granted")
+	assert.True(t, ok)
+	assert.Equal(t, "project", value)
+
+	// The grants of the next sync request the resource again.
+	_, ok = listed.Take("granted")
+	assert.False(t, ok)
+
+	listed.Put("expired", "project")
+	now = now.Add(time.Minute)
+	_, ok = listed.Take("expired")
+	assert.False(t, ok)
+
+	// The resources never granted are dropped once they expire.
+	listed.Put("forgotten", "project")
+	now = now.Add(time.Minute)
+	listed.Put("listed", "project")
+	assert.Len(t, listed.entries, 1)
+}
@@ -0,0 +1,114 @@
+package connector
+
+import (
+	"fmt"
+	"slices"
+
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+)
+
+// rolePermission is something a role lets its team members do in a business.
+type rolePermission struct {
+	Slug        string
+	DisplayName string
+	Description string
+}
+
+// rolePermissions are the permissions the roles are mapped to. FreshBooks has no endpoint to request what each role
+// allows, and its help pages describe the roles in prose that changes with the plan of the business, so they are an
+// approximation maintained by hand, only used with WithRolePermissions. The descriptions say so.
+var rolePermissions = []rolePermission{
+	{Slug: "manage_billing", DisplayName: "Manage billing", Description: "Manage the FreshBooks subscription and billing of the business"},
+	{Slug: "manage_team", DisplayName: "Manage team", Description: "Invite team members, change their roles and deactivate them"},
+	{Slug: "manage_clients", DisplayName: "Manage clients", Description: "Create, edit and archive clients"},
+	{Slug: "manage_invoices", DisplayName: "Manage invoices", Description: "Create, send and edit invoices and estimates"},
+	{Slug: "manage_payments", DisplayName: "Manage payments", Description: "Record, edit and refund payments"},
+	{Slug: "manage_expenses", DisplayName: "Manage expenses", Description: "Record and edit expenses and bills"},
+	{Slug: "manage_bank_connections", DisplayName: "Manage bank connections", Description: "Connect bank accounts and reconcile their transactions"},
+	{Slug: "manage_accounting", DisplayName: "Manage accounting", Description: "Edit the chart of accounts and the journal entries"},
+	{Slug: "manage_payroll", DisplayName: "Manage payroll", Description: "Run payroll and edit the pay of the employees"},
+	{Slug: "view_reports", DisplayName: "View reports", Description: "View the financial and accounting reports"},
+	{Slug: "manage_projects", DisplayName: "Manage projects", Description: "Create projects and manage their teams"},
+	{Slug: "track_time", DisplayName: "Track time", Description: "Log time against the projects of the business"},
+}
+
+// rolePermissionMatrix maps the business role name of each known role to the slugs of the permissions it has.
+// The roles that aren't known have no permissions, since there is no telling what they allow.
+var rolePermissionMatrix = map[string][]string{
+	ownerRoleName: {
+		"manage_billing", "manage_team", "manage_clients", "manage_invoices", "manage_payments", "manage_expenses",
+		"manage_bank_connections", "manage_accounting", "manage_payroll", "view_reports", "manage_projects", "track_time",
+	},
+	"business_manager": {
+		"manage_team", "manage_clients", "manage_invoices", "manage_payments", "manage_expenses", "view_reports",
+		"manage_projects", "track_time",
+	},
+	"business_employee": {"manage_expenses", "track_time"},
+	"contractor":        {"track_time"},
+	"no_seat_employee": {
+		"manage_invoices", "manage_payments", "manage_expenses", "manage_bank_connections", "manage_accounting",
+		"view_reports",
+	},
+}
+
+// permissionsOf returns the permissions of a role, in the order of rolePermissions.
+func permissionsOf(businessRoleName string) []rolePermission {
+	slugs := rolePermissionMatrix[businessRoleName]
+
+	var ret []rolePermission
+	for _, permission := range rolePermissions {
+		if slices.Contains(slugs, permission.Slug) {
+			ret = append(ret, permission)
+		}
+	}
+
+	return ret
+}
+
+// isRolePermission reports whether the slug is the one of a permission rather than the assignment of a role.
+func isRolePermission(slug string) bool {
+	return slices.ContainsFunc(rolePermissions, func(permission rolePermission) bool {
+		return permission.Slug == slug
+	})
+}
+
+// approximatePermissionNote ends the description of every permission, since the matrix isn't taken from FreshBooks.
+const approximatePermissionNote = " (approximate, from a hand-maintained table of the FreshBooks roles)"
+
+// permissionEntitlements returns an entitlement for each permission of the role, granted to the role itself.
+func permissionEntitlements(resource *v2.Resource, businessRoleName string) []*v2.Entitlement {
+	var ret []*v2.Entitlement
+	for _, permission := range permissionsOf(businessRoleName) {
+		ret = append(ret, entitlement.NewPermissionEntitlement(
+			resource,
+			permission.Slug,
+			entitlement.WithGrantableTo(roleResourceType),
+			entitlement.WithDescription(permission.Description+approximatePermissionNote),
+			entitlement.WithDisplayName(fmt.Sprintf("%s role: %s", resource.DisplayName, permission.DisplayName)),
+		))
+	}
+
+	return ret
+}
+
+// permissionGrants grants each permission of the role to the role, expanded to the users that are assigned the role,
+// so the permissions of a user follow from its role.
+func permissionGrants(resource *v2.Resource, businessRoleName string) []*v2.Grant {
+	assignedID := entitlement.NewEntitlementID(resource, permissionName)
+
+	var ret []*v2.Grant
+	for _, permission := range permissionsOf(businessRoleName) {
+		ret = append(ret, grant.NewGrant(
+			resource,
+			permission.Slug,
+			resource.Id,
+			grant.WithAnnotation(&v2.GrantExpandable{
+				EntitlementIds: []string{assignedID},
+			}),
+		))
+	}
+
+	return ret
+}
@@ -0,0 +1,205 @@
+package connector
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
+	"github.com/conductorone/baton-sdk/pkg/types/grant"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+const (
+	projectMemberEntitlement = "member"
+	projectOwnerEntitlement  = "owner"
+)
+
+type projectBuilder struct {
+	resourceType *v2.ResourceType
+	projects     *listedResources[client.Project]
+	client       *client.FreshBooksClient
+	teamMembers  *teamMemberCache
+}
+
+func (p *projectBuilder) ResourceType(_ context.Context) *v2.ResourceType {
+	return projectResourceType
+}
+
+// List returns the projects of a business. The members of each project team are kept to be granted later.
+func (p *projectBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	var rv []*v2.Resource
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	bag, pageToken, err := getToken(pToken, projectResourceType)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	projects, nextPageToken, annotation, err := p.client.ListProjects(ctx, parentResourceID.Resource, client.PageOptions{
+		Page:    pageToken,
+		PerPage: pToken.Size,
+	})
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, project := range projects {
+		projectResource, err := parseIntoProjectResource(project, parentResourceID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		p.projects.Put(projectResource.Id.Resource, project)
+		rv = append(rv, projectResource)
+	}
+
+	nextPageToken, err = bag.Marshal()
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	return rv, nextPageToken, annotation, nil
+}
+
+// Entitlements returns the membership and the ownership of the project team.
+func (p *projectBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
+	ret := []*v2.Entitlement{
+		entitlement.NewAssignmentEntitlement(
+			resource,
+			projectMemberEntitlement,
+			entitlement.WithGrantableTo(userResourceType),
+			entitlement.WithDescription(fmt.Sprintf("Member of the %s project team", resource.DisplayName)),
+			entitlement.WithDisplayName(fmt.Sprintf("%s project member", resource.DisplayName)),
+		),
+		entitlement.NewPermissionEntitlement(
+			resource,
+			projectOwnerEntitlement,
+			entitlement.WithGrantableTo(userResourceType),
+			entitlement.WithDescription(fmt.Sprintf("Owner of the %s project", resource.DisplayName)),
+			entitlement.WithDisplayName(fmt.Sprintf("%s project owner", resource.DisplayName)),
+		),
+	}
+
+	return ret, "", nil, nil
+}
+
+// Grants returns a membership grant for every member of the project team, plus an ownership grant for its owners.
+// Project members are matched with the users of the business through their identity.
+func (p *projectBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
+	var ret []*v2.Grant
+	l := ctxzap.Extract(ctx)
+
+	businessID := resource.ParentResourceId.Resource
+
+	project, ok := p.projects.Take(resource.Id.Resource)
+	if !ok {
+		requestedProject, _, err := p.client.GetProject(ctx, businessID, resource.Id.Resource)
+		if err != nil {
+			return nil, "", nil, err
+		}
+		project = *requestedProject
+	}
+
+	teamMembers, annotation, err := p.teamMembers.Get(ctx, businessID)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	for _, member := range project.Group.Members {
+		teamMember, ok := findTeamMember(teamMembers, member)
+		if !ok {
+			l.Debug(
+				"project member is not a team member of the business, skipping",
+				zap.String("project_id", resource.Id.Resource),
+				zap.Int64("identity_id", member.IdentityID),
+			)
+			continue
+		}
+
+		userID, err := rs.NewResourceID(userResourceType, teamMember.UUID)
+		if err != nil {
+			return nil, "", nil, err
+		}
+
+		ret = append(ret, grant.NewGrant(resource, projectMemberEntitlement, userID))
+		if member.Role == projectOwnerEntitlement {
+			ret = append(ret, grant.NewGrant(resource, projectOwnerEntitlement, userID))
+		}
+	}
+
+	return ret, "", annotation, nil
+}
+
+// findTeamMember looks for the Team Member behind a project member, by identity or, when the identity is missing, by email.
+func findTeamMember(teamMembers []client.TeamMember, member client.ProjectMember) (client.TeamMember, bool) {
+	for _, teamMember := range teamMembers {
+		if member.IdentityID != 0 && teamMember.IdentityID == member.IdentityID {
+			return teamMember, true
+		}
+
+		if member.IdentityID == 0 && member.Email != "" && strings.EqualFold(teamMember.Email, member.Email) {
+			return teamMember, true
+		}
+	}
+
+	return client.TeamMember{}, false
+}
+
+// parseIntoProjectResource parses a Project from FreshBooks into a Project Resource of the given business.
+func parseIntoProjectResource(project client.Project, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
+	profile := map[string]interface{}{
+		"id":          project.ID,
+		"title":       project.Title,
+		"active":      project.Active,
+		"complete":    project.Complete,
+		"client_id":   project.ClientID,
+		"business_id": parentResourceID.Resource,
+	}
+
+	groupTraits := []rs.GroupTraitOption{
+		rs.WithGroupProfile(profile),
+	}
+
+	displayName := project.Title
+	if displayName == "" {
+		displayName = strconv.FormatInt(project.ID, 10)
+	}
+
+	ret, err := rs.NewGroupResource(
+		displayName,
+		projectResourceType,
+		project.ID,
+		groupTraits,
+		rs.WithParentResourceID(parentResourceID),
+		rs.WithDescription(project.Description),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	return ret, nil
+}
+
+func newProjectBuilder(c *client.FreshBooksClient, teamMembers *teamMemberCache) *projectBuilder {
+	return &projectBuilder{
+		resourceType: projectResourceType,
+		projects:     newListedResources[client.Project](listedResourceTTL),
+		client:       c,
+		teamMembers:  teamMembers,
+	}
+}
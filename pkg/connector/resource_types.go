@@ -15,3 +15,26 @@ var roleResourceType = &v2.ResourceType{
 	DisplayName: "Role",
 	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_ROLE},
 }
+
+var businessResourceType = &v2.ResourceType{
+	Id:          "business",
+	DisplayName: "Business",
+}
+
+var projectResourceType = &v2.ResourceType{
+	Id:          "project",
+	DisplayName: "Project",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
+}
+
+var clientResourceType = &v2.ResourceType{
+	Id:          "client",
+	DisplayName: "Client",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_GROUP},
+}
+
+var clientContactResourceType = &v2.ResourceType{
+	Id:          "client_contact",
+	DisplayName: "Client Contact",
+	Traits:      []v2.ResourceType_Trait{v2.ResourceType_TRAIT_USER},
+}
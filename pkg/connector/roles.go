@@ -2,7 +2,7 @@ package connector
 
 import (
 	"context"
-	"sync"
+	"slices"
 
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
@@ -10,139 +10,291 @@ import (
 	"github.com/conductorone/baton-sdk/pkg/types/entitlement"
 	"github.com/conductorone/baton-sdk/pkg/types/grant"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/conductorone/baton-freshbooks/pkg/client"
 )
 
-const permissionName = "assigned"
+const (
+	permissionName = "assigned"
+
+	// ownerRoleName is the role of the owner of a business, it can't be granted nor revoked.
+	ownerRoleName = "owner"
+	// DefaultRoleName is the role the users are moved to when their role is revoked, unless another one is configured.
+	DefaultRoleName = "business_employee"
+)
+
+// availableRoles are the known roles a team member of a business can have. The platform doesn't allow to modify
+// or create them, and they cannot be requested to the API, but newer plans may add roles that aren't listed here.
+var availableRoles = []client.Role{
+	{RoleName: "admin", BusinessRoleName: ownerRoleName},           // Admin Role.
+	{RoleName: "manager", BusinessRoleName: "business_manager"},    // Manager Role.
+	{RoleName: "employee", BusinessRoleName: "business_employee"},  // Employee Role.
+	{RoleName: "contractor", BusinessRoleName: "contractor"},       // Contractor Role.
+	{RoleName: "accountant", BusinessRoleName: "no_seat_employee"}, // Accountant Role.
+}
 
 type roleBuilder struct {
-	resourceType     *v2.ResourceType
-	teamMembers      []client.TeamMember
-	teamMembersMutex sync.RWMutex
-	client           *client.FreshBooksClient
+	resourceType    *v2.ResourceType
+	client          *client.FreshBooksClient
+	teamMembers     *teamMemberCache
+	defaultRole     string
+	rolePermissions bool
 }
 
 func (r *roleBuilder) ResourceType(_ context.Context) *v2.ResourceType {
 	return roleResourceType
 }
 
-// List retrieves a hardcoded list of available Roles, since they are fixed (not modifications neither creation allowed by the platform) and cannot be requested to the API.
-func (r *roleBuilder) List(_ context.Context, _ *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	availableRoles := []client.Role{
-		{RoleName: "admin", BusinessRoleName: "owner"},                 // Admin Role.
-		{RoleName: "manager", BusinessRoleName: "business_manager"},    // Manager Role.
-		{RoleName: "employee", BusinessRoleName: "business_employee"},  // Employee Role.
-		{RoleName: "contractor", BusinessRoleName: "contractor"},       // Contractor Role.
-		{RoleName: "accountant", BusinessRoleName: "no_seat_employee"}, // Accountant Role.
+// List returns the Roles of a business: the known Roles, followed by every other role its team members have.
+// FreshBooks has no endpoint to list the roles, so a role that isn't known, like one added to a newer plan,
+// is only found through the team members that have it. It is still listed, for them to get their grant.
+// The Roles are listed once per business, since the members of each role differ between businesses.
+func (r *roleBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, _ *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
+	if parentResourceID == nil {
+		return nil, "", nil, nil
+	}
+
+	l := ctxzap.Extract(ctx)
+
+	teamMembers, annotation, err := r.teamMembers.Get(ctx, parentResourceID.Resource)
+	if err != nil {
+		return nil, "", annotation, err
+	}
+
+	observedRoles := observedRoles(teamMembers)
+	for _, role := range observedRoles {
+		l.Warn(
+			"team members have a role that isn't known, listing it anyway",
+			zap.String("business_id", parentResourceID.Resource),
+			zap.String("business_role_name", role.BusinessRoleName),
+		)
 	}
 
 	var ret []*v2.Resource
-	for _, role := range availableRoles {
-		roleResource, err := parseIntoRoleResource(role, nil)
+	for _, role := range append(slices.Clone(availableRoles), observedRoles...) {
+		roleResource, err := parseIntoRoleResource(role, parentResourceID)
 		if err != nil {
-			return nil, "", nil, err
+			return nil, "", annotation, err
 		}
 
 		ret = append(ret, roleResource)
 	}
 
-	return ret, "", nil, nil
+	return ret, "", annotation, nil
 }
 
+// Entitlements returns the assignment of the Role, along with a permission entitlement for each thing it lets
+// its team members do when the role permissions are enabled.
 func (r *roleBuilder) Entitlements(_ context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Entitlement, string, annotations.Annotations, error) {
 	var ret []*v2.Entitlement
 
+	_, businessRoleName, err := parseRoleResourceID(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
 	assigmentOptions := []entitlement.EntitlementOption{
 		entitlement.WithGrantableTo(userResourceType),
 		entitlement.WithDescription(resource.Description),
 		entitlement.WithDisplayName(resource.DisplayName),
 	}
 	ret = append(ret, entitlement.NewPermissionEntitlement(resource, permissionName, assigmentOptions...))
+	if r.rolePermissions {
+		ret = append(ret, permissionEntitlements(resource, businessRoleName)...)
+	}
 
 	return ret, "", nil, nil
 }
 
+// Grants returns the assignment of the Role to each of its active team members, and, when the role permissions are
+// enabled, the permissions of the Role granted to the Role itself, expanded to its team members.
+// A deactivated team member keeps its role in FreshBooks but can't use it, so it isn't granted, like the event feed
+// revokes it, and Grant refuses it.
 func (r *roleBuilder) Grants(ctx context.Context, resource *v2.Resource, _ *pagination.Token) ([]*v2.Grant, string, annotations.Annotations, error) {
 	var ret []*v2.Grant
 
-	teamMembers, err := r.GetAllTeamMembers(ctx)
+	businessID, businessRoleName, err := parseRoleResourceID(resource.Id.Resource)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	teamMembers, annotation, err := r.teamMembers.Get(ctx, businessID)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
 	for _, teamMember := range teamMembers {
-		if teamMember.BusinessRoleName == resource.Id.Resource {
-			userResource, err := parseIntoUserResource(teamMember, nil)
+		if teamMember.Active && teamMember.BusinessRoleName == businessRoleName {
+			principalID, err := rs.NewResourceID(userResourceType, teamMember.UUID)
 			if err != nil {
 				return nil, "", nil, err
 			}
 
-			membershipGrant := grant.NewGrant(resource, permissionName, userResource.Id)
+			membershipGrant := grant.NewGrant(resource, permissionName, principalID)
 			ret = append(ret, membershipGrant)
 		}
 	}
 
-	return ret, "", nil, nil
+	if r.rolePermissions {
+		ret = append(ret, permissionGrants(resource, businessRoleName)...)
+	}
+
+	return ret, "", annotation, nil
+}
+
+// Grant moves the team member onto the role, replacing the role it had, since a team member has a single role per business.
+// A deactivated team member is refused, since the role would never show up as granted to it.
+func (r *roleBuilder) Grant(ctx context.Context, principal *v2.Resource, entitlement *v2.Entitlement) ([]*v2.Grant, annotations.Annotations, error) {
+	if principal.Id.ResourceType != userResourceType.Id {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "baton-freshbooks: only users can be granted a role, got %s", principal.Id.ResourceType)
+	}
+
+	if isRolePermission(entitlementSlug(entitlement)) {
+		return nil, nil, status.Error(codes.FailedPrecondition, "baton-freshbooks: the permissions of a role can't be granted on their own, grant the role instead")
+	}
+
+	businessID, businessRoleName, err := parseRoleResourceID(entitlement.Resource.Id.Resource)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if businessRoleName == ownerRoleName {
+		return nil, nil, status.Error(codes.FailedPrecondition, "baton-freshbooks: the owner role can't be granted, the ownership of a business can only be transferred from FreshBooks")
+	}
+
+	teamMember, annotation, err := r.client.GetTeamMember(ctx, businessID, principal.Id.Resource)
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	if !teamMember.Active {
+		return nil, annotation, status.Errorf(codes.FailedPrecondition, "baton-freshbooks: %s is deactivated, reactivate it in FreshBooks before granting it a role", principal.Id.Resource)
+	}
+
+	membershipGrant := grant.NewGrant(entitlement.Resource, permissionName, principal.Id)
+
+	switch teamMember.BusinessRoleName {
+	case businessRoleName:
+		annotation.Update(&v2.GrantAlreadyExists{})
+		return []*v2.Grant{membershipGrant}, annotation, nil
+	case ownerRoleName:
+		return nil, annotation, status.Errorf(codes.FailedPrecondition, "baton-freshbooks: %s is the owner of the business, its role can't be changed", principal.Id.Resource)
+	}
+
+	_, annotation, err = r.client.UpdateTeamMember(ctx, businessID, principal.Id.Resource, client.TeamMemberUpdate{
+		BusinessRoleName: businessRoleName,
+	})
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	r.teamMembers.Invalidate(businessID)
+
+	return []*v2.Grant{membershipGrant}, annotation, nil
 }
 
-func (r *roleBuilder) GetAllTeamMembers(ctx context.Context) ([]client.TeamMember, error) {
-	r.teamMembersMutex.Lock()
-	defer r.teamMembersMutex.Unlock()
+// Revoke moves the team member onto the default role. The owner role and the default role itself can't be revoked.
+func (r *roleBuilder) Revoke(ctx context.Context, grant *v2.Grant) (annotations.Annotations, error) {
+	principal := grant.Principal
+	if principal.Id.ResourceType != userResourceType.Id {
+		return nil, status.Errorf(codes.InvalidArgument, "baton-freshbooks: only users can have a role revoked, got %s", principal.Id.ResourceType)
+	}
 
-	var ret []client.TeamMember
-	if len(r.teamMembers) > 0 {
-		return r.teamMembers, nil
+	if isRolePermission(entitlementSlug(grant.Entitlement)) {
+		return nil, status.Error(codes.FailedPrecondition, "baton-freshbooks: the permissions of a role can't be revoked on their own, revoke the role instead")
 	}
 
-	err := r.client.EnsureBusinessID(ctx)
+	businessID, businessRoleName, err := parseRoleResourceID(grant.Entitlement.Resource.Id.Resource)
 	if err != nil {
 		return nil, err
 	}
 
-	paginationToken := pagination.Token{Size: 50, Token: ""}
-	for {
-		bag, pageToken, err := getToken(&paginationToken, userResourceType)
-		if err != nil {
-			return nil, err
-		}
+	switch businessRoleName {
+	case ownerRoleName:
+		return nil, status.Error(codes.FailedPrecondition, "baton-freshbooks: the owner role can't be revoked")
+	case r.defaultRole:
+		return nil, status.Errorf(codes.FailedPrecondition, "baton-freshbooks: %s is the default role and can't be revoked, grant another role instead", businessRoleName)
+	}
 
-		teamMembers, nextPageToken, _, err := r.client.ListTeamMembers(ctx, client.PageOptions{
-			Page:    pageToken,
-			PerPage: paginationToken.Size,
-		})
-		if err != nil {
-			return nil, err
-		}
+	teamMember, annotation, err := r.client.GetTeamMember(ctx, businessID, principal.Id.Resource)
+	if err != nil {
+		return annotation, err
+	}
 
-		ret = append(ret, teamMembers...)
+	if teamMember.BusinessRoleName != businessRoleName {
+		annotation.Update(&v2.GrantAlreadyRevoked{})
+		return annotation, nil
+	}
 
-		err = bag.Next(nextPageToken)
-		if err != nil {
-			return nil, err
+	_, annotation, err = r.client.UpdateTeamMember(ctx, businessID, principal.Id.Resource, client.TeamMemberUpdate{
+		BusinessRoleName: r.defaultRole,
+	})
+	if err != nil {
+		return annotation, err
+	}
+
+	r.teamMembers.Invalidate(businessID)
+
+	return annotation, nil
+}
+
+// observedRoles returns the roles the team members have that aren't one of the available Roles, sorted by name.
+// Their business role name is used as the name of the Role as well, since there is nothing better to call them.
+func observedRoles(teamMembers []client.TeamMember) []client.Role {
+	var businessRoleNames []string
+	for _, teamMember := range teamMembers {
+		businessRoleName := teamMember.BusinessRoleName
+		if businessRoleName == "" || isAvailableRole(businessRoleName) || slices.Contains(businessRoleNames, businessRoleName) {
+			continue
 		}
 
-		if nextPageToken == "" {
-			break
+		businessRoleNames = append(businessRoleNames, businessRoleName)
+	}
+	slices.Sort(businessRoleNames)
+
+	ret := make([]client.Role, 0, len(businessRoleNames))
+	for _, businessRoleName := range businessRoleNames {
+		ret = append(ret, client.Role{RoleName: businessRoleName, BusinessRoleName: businessRoleName})
+	}
+
+	return ret
+}
+
+// isAvailableRole reports whether the business role name is one of the available Roles.
+func isAvailableRole(businessRoleName string) bool {
+	for _, role := range availableRoles {
+		if role.BusinessRoleName == businessRoleName {
+			return true
 		}
-		paginationToken.Token = nextPageToken
 	}
 
-	return ret, nil
+	return false
 }
 
-// parseIntoRoleResource parses a role from FreshBooks into a Role Resource.
-func parseIntoRoleResource(role client.Role, _ *v2.ResourceId) (*v2.Resource, error) {
+// parseIntoRoleResource parses a role from FreshBooks into a Role Resource of the given business.
+func parseIntoRoleResource(role client.Role, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
 	profile := map[string]interface{}{
-		"id":   role.BusinessRoleName,
-		"name": role.RoleName,
+		"id":          role.BusinessRoleName,
+		"name":        role.RoleName,
+		"business_id": parentResourceID.Resource,
+		"known":       isAvailableRole(role.BusinessRoleName),
 	}
 
 	roleTraits := []rs.RoleTraitOption{
 		rs.WithRoleProfile(profile),
 	}
 
-	ret, err := rs.NewRoleResource(role.RoleName, roleResourceType, role.BusinessRoleName, roleTraits)
+	ret, err := rs.NewRoleResource(
+		role.RoleName,
+		roleResourceType,
+		roleResourceID(parentResourceID.Resource, role.BusinessRoleName),
+		roleTraits,
+		rs.WithParentResourceID(parentResourceID),
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -150,9 +302,12 @@ func parseIntoRoleResource(role client.Role, _ *v2.ResourceId) (*v2.Resource, er
 	return ret, nil
 }
 
-func newRoleBuilder(c *client.FreshBooksClient) *roleBuilder {
+func newRoleBuilder(c *client.FreshBooksClient, teamMembers *teamMemberCache, defaultRole string, rolePermissions bool) *roleBuilder {
 	return &roleBuilder{
-		resourceType: roleResourceType,
-		client:       c,
+		resourceType:    roleResourceType,
+		client:          c,
+		teamMembers:     teamMembers,
+		defaultRole:     defaultRole,
+		rolePermissions: rolePermissions,
 	}
 }
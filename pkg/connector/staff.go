@@ -0,0 +1,207 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	// staffPageStateID identifies the page state of the legacy staff, which are listed once the team members are done.
+	staffPageStateID = "staff"
+
+	// staffUserResourcePrefix starts the ID of the User Resources of the legacy staff, which are only unique inside
+	// their accounting account, so they never collide with the UUID of a team member.
+	staffUserResourcePrefix = "staff:"
+
+	// staffVisStateActive is the vis_state of the staff that weren't deleted.
+	staffVisStateActive = 0
+
+	// The source in the profile of a user tells which API it was listed from.
+	userSourceTeamMember = "team_member"
+	userSourceStaff      = "staff"
+)
+
+// listStaffPage returns the users of a page of the legacy staff of the accounting account of a business, leaving
+// out the staff that are also team members, and moves the bag to the next page.
+// The businesses without an accounting account, or whose account has no staff API, have no legacy staff.
+func (u *userBuilder) listStaffPage(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	bag *pagination.Bag,
+	pageSize int,
+) ([]*v2.Resource, annotations.Annotations, error) {
+	l := ctxzap.Extract(ctx)
+
+	accountID, err := u.client.AccountID(ctx, parentResourceID.Resource)
+	if errors.Is(err, client.ErrNoAccountingAccount) {
+		l.Debug("business has no accounting account, skipping its legacy staff", zap.String("business_id", parentResourceID.Resource))
+		return nil, nil, bag.Next("")
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	page := 0
+	if bag.PageToken() != "" {
+		page, err = strconv.Atoi(bag.PageToken())
+		if err != nil {
+			return nil, nil, err
+		}
+	}
+
+	staffs, nextPageToken, annotation, err := u.client.ListStaff(ctx, accountID, client.PageOptions{
+		Page:    page,
+		PerPage: pageSize,
+	})
+	switch status.Code(err) {
+	case codes.OK:
+	case codes.NotFound, codes.PermissionDenied:
+		l.Warn("the legacy staff of the accounting account can't be listed, skipping them",
+			zap.String("business_id", parentResourceID.Resource),
+			zap.String("account_id", accountID),
+			zap.Error(err),
+		)
+		return nil, annotation, bag.Next("")
+	default:
+		return nil, annotation, err
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	teamMembers, _, err := u.teamMembers.Get(ctx, parentResourceID.Resource)
+	if err != nil {
+		return nil, annotation, err
+	}
+	matcher := newTeamMemberMatcher(teamMembers)
+
+	var rv []*v2.Resource
+	for _, staff := range staffs {
+		if matcher.matches(staff) {
+			continue
+		}
+
+		userResource, err := parseIntoStaffUserResource(staff, accountID, parentResourceID, u.classifier)
+		if err != nil {
+			return nil, annotation, err
+		}
+		rv = append(rv, userResource)
+	}
+
+	return rv, annotation, nil
+}
+
+// teamMemberMatcher tells whether a legacy staff is also a team member, by its email or its identity.
+type teamMemberMatcher struct {
+	emails      map[string]bool
+	identityIDs map[int64]bool
+}
+
+func newTeamMemberMatcher(teamMembers []client.TeamMember) teamMemberMatcher {
+	matcher := teamMemberMatcher{
+		emails:      make(map[string]bool, len(teamMembers)),
+		identityIDs: make(map[int64]bool, len(teamMembers)),
+	}
+	for _, teamMember := range teamMembers {
+		if teamMember.Email != "" {
+			matcher.emails[strings.ToLower(teamMember.Email)] = true
+		}
+		if teamMember.IdentityID != 0 {
+			matcher.identityIDs[teamMember.IdentityID] = true
+		}
+	}
+
+	return matcher
+}
+
+func (m teamMemberMatcher) matches(staff client.Staff) bool {
+	return (staff.Email != "" && m.emails[strings.ToLower(staff.Email)]) ||
+		(staff.IdentityID != 0 && m.identityIDs[staff.IdentityID])
+}
+
+// staffUserResourceID builds the ID of the User Resource of a legacy staff.
+func staffUserResourceID(accountID string, staffID int64) string {
+	return staffUserResourcePrefix + accountID + ":" + strconv.FormatInt(staffID, 10)
+}
+
+// isStaffUserResourceID reports whether the ID of a User Resource is the one of a legacy staff.
+func isStaffUserResourceID(id string) bool {
+	return strings.HasPrefix(id, staffUserResourcePrefix)
+}
+
+// parseIntoStaffUserResource parses a legacy Staff of an accounting account into a User Resource.
+// Deleted staff are disabled. Like the team members, the staff are people, and the external ones are flagged in the profile.
+func parseIntoStaffUserResource(staff client.Staff, accountID string, parentResourceID *v2.ResourceId, classifier accountClassifier) (*v2.Resource, error) {
+	userStatus, statusDetails := v2.UserTrait_Status_STATUS_ENABLED, userStatusActive
+	if staff.VisState != staffVisStateActive {
+		userStatus, statusDetails = v2.UserTrait_Status_STATUS_DISABLED, userStatusInactive
+	}
+
+	profile := map[string]interface{}{
+		"staff_id":   staff.ID,
+		"account_id": accountID,
+		"email":      staff.Email,
+		"first_name": staff.FirstName,
+		"last_name":  staff.LastName,
+		"username":   staff.Username,
+		"level":      staff.Level,
+		"status":     statusDetails,
+		"updated_at": staff.Updated,
+		"source":     userSourceStaff,
+		"external":   classifier.isExternal(staff.Email, ""),
+	}
+
+	login := staff.Username
+	if login == "" {
+		login = staff.Email
+	}
+
+	userTraits := []rs.UserTraitOption{
+		rs.WithUserProfile(profile),
+		rs.WithDetailedStatus(userStatus, statusDetails),
+		rs.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_HUMAN),
+		rs.WithUserLogin(login),
+	}
+
+	if staff.Email != "" {
+		userTraits = append(userTraits, rs.WithEmail(staff.Email, true))
+	}
+
+	if signupDate, ok := parseTimestamp(staff.SignupDate); ok {
+		userTraits = append(userTraits, rs.WithCreatedAt(signupDate))
+	}
+
+	if lastLogin, ok := parseTimestamp(staff.LastLogin); ok {
+		userTraits = append(userTraits, rs.WithLastLogin(lastLogin))
+	}
+
+	displayName := staff.DisplayName
+	if displayName == "" {
+		displayName = strings.TrimSpace(staff.FirstName + " " + staff.LastName)
+	}
+	if displayName == "" {
+		displayName = login
+	}
+
+	return rs.NewUserResource(
+		displayName,
+		userResourceType,
+		staffUserResourceID(accountID, staff.ID),
+		userTraits,
+		rs.WithParentResourceID(parentResourceID),
+	)
+}
@@ -0,0 +1,215 @@
+package connector
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+)
+
+const (
+	teamMemberStateVersion = 1
+
+	// teamMemberFullRefreshInterval is how long the team members of a business are only requested when they changed,
+	// before every one of them is requested again. A team member removed from a business has no update to report,
+	// so it only goes away with the full requests.
+	teamMemberFullRefreshInterval = 24 * time.Hour
+)
+
+// teamMemberSnapshot is what is kept of the team members of a business between syncs.
+type teamMemberSnapshot struct {
+	TeamMembers []client.TeamMember `json:"team_members"`
+	// HighWaterMark is the latest creation or update date of the team members, taken from FreshBooks so it doesn't
+	// depend on the clock of the connector. The next requests only ask for the team members updated since then.
+	HighWaterMark time.Time `json:"high_water_mark"`
+	// FullFetchedAt is when every team member was last requested.
+	FullFetchedAt time.Time `json:"full_fetched_at"`
+}
+
+// eventBaseline is the role of every active team member of a business, keyed by its UUID, when the event feed
+// last requested them, along with the start of the events of the cursor it returned then.
+type eventBaseline struct {
+	Since time.Time         `json:"since"`
+	Roles map[string]string `json:"roles"`
+}
+
+// teamMemberStateFile is the content of the file written by teamMemberState.
+type teamMemberStateFile struct {
+	Version    int                           `json:"version"`
+	Businesses map[string]teamMemberSnapshot `json:"businesses"`
+	Events     map[string]eventBaseline      `json:"events,omitempty"`
+}
+
+// teamMemberState keeps the team members of each business in a file, so the next sync only requests the ones
+// that changed since the previous one, and the event feed only reports the roles that changed since its last call.
+// The file holds the names and emails of the team members, so it is encrypted like the token store.
+// Without a file, it is only kept in memory.
+type teamMemberState struct {
+	file *client.SealedFile
+
+	mu         sync.Mutex
+	businesses map[string]teamMemberSnapshot
+	events     map[string]eventBaseline
+}
+
+// newTeamMemberState returns a state loaded from the file at path, encrypted with passphrase, or kept in memory
+// only when path is empty. A missing file, or one written by another version of the connector, is an empty state.
+func newTeamMemberState(path, passphrase string) (*teamMemberState, error) {
+	state := &teamMemberState{
+		businesses: make(map[string]teamMemberSnapshot),
+		events:     make(map[string]eventBaseline),
+	}
+	if path == "" {
+		return state, nil
+	}
+
+	file, err := client.NewSealedFile(path, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("error opening team member state: %w", err)
+	}
+	state.file = file
+
+	var content teamMemberStateFile
+	_, err = file.Load(&content)
+	if err != nil {
+		return nil, fmt.Errorf("error loading team member state: %w", err)
+	}
+
+	if content.Version == teamMemberStateVersion && content.Businesses != nil {
+		state.businesses = content.Businesses
+	}
+	if content.Version == teamMemberStateVersion && content.Events != nil {
+		state.events = content.Events
+	}
+
+	return state, nil
+}
+
+// Load returns the snapshot of the team members of a business, if one was saved.
+func (s *teamMemberState) Load(businessID string) (teamMemberSnapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snapshot, ok := s.businesses[businessID]
+	return snapshot, ok
+}
+
+// Save keeps the snapshot of the team members of a business and writes the whole state to its file.
+func (s *teamMemberState) Save(businessID string, snapshot teamMemberSnapshot) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.businesses[businessID] = snapshot
+
+	return s.write()
+}
+
+// LoadEventBaseline returns the roles of the team members of a business on the last call of the event feed, if
+// it was saved.
+func (s *teamMemberState) LoadEventBaseline(businessID string) (eventBaseline, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	baseline, ok := s.events[businessID]
+	return baseline, ok
+}
+
+// SaveEventBaselines keeps the roles of the team members of each business seen by the event feed, and writes the
+// whole state to its file.
+func (s *teamMemberState) SaveEventBaselines(baselines map[string]eventBaseline) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for businessID, baseline := range baselines {
+		s.events[businessID] = baseline
+	}
+
+	return s.write()
+}
+
+// write saves the state to its file, if it has one. The mutex must be held.
+func (s *teamMemberState) write() error {
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Save(teamMemberStateFile{
+		Version:    teamMemberStateVersion,
+		Businesses: s.businesses,
+		Events:     s.events,
+	})
+	if err != nil {
+		return fmt.Errorf("error saving team member state: %w", err)
+	}
+
+	return nil
+}
+
+// teamMembersHighWaterMark returns the latest creation or update date of the team members, or a zero time when
+// none of them has one.
+func teamMembersHighWaterMark(teamMembers []client.TeamMember) time.Time {
+	var mark time.Time
+	for _, teamMember := range teamMembers {
+		for _, value := range []string{teamMember.CreatedAt, teamMember.UpdatedAt} {
+			t, ok := parseTimestamp(value)
+			if ok && t.After(mark) {
+				mark = t
+			}
+		}
+	}
+
+	return mark
+}
+
+// isFullTeamMemberList reports whether the team members requested with the updated_since filter, from the
+// high-water mark of the previous snapshot, can replace it as the full list of the team members.
+// It is the case when FreshBooks ignored the filter, which shows as a team member last changed before the mark, and
+// when every team member of the previous snapshot is in the answer. The latter is also what every team member
+// changing since the previous request looks like, which can't be told apart from an ignored filter but makes the
+// answer just as complete.
+func isFullTeamMemberList(changed []client.TeamMember, previous teamMemberSnapshot) bool {
+	for _, teamMember := range changed {
+		if mark := teamMembersHighWaterMark([]client.TeamMember{teamMember}); !mark.IsZero() && mark.Before(previous.HighWaterMark) {
+			return true
+		}
+	}
+
+	if len(changed) == 0 {
+		return false
+	}
+
+	answered := make(map[string]bool, len(changed))
+	for _, teamMember := range changed {
+		answered[teamMember.UUID] = true
+	}
+	for _, teamMember := range previous.TeamMembers {
+		if !answered[teamMember.UUID] {
+			return false
+		}
+	}
+
+	return true
+}
+
+// mergeTeamMembers replaces the kept team members by their changed version, and adds the new ones after them.
+func mergeTeamMembers(kept, changed []client.TeamMember) []client.TeamMember {
+	index := make(map[string]int, len(kept))
+	merged := make([]client.TeamMember, len(kept), len(kept)+len(changed))
+	for i, teamMember := range kept {
+		index[teamMember.UUID] = i
+		merged[i] = teamMember
+	}
+
+	for _, teamMember := range changed {
+		if i, ok := index[teamMember.UUID]; ok {
+			merged[i] = teamMember
+			continue
+		}
+
+		index[teamMember.UUID] = len(merged)
+		merged = append(merged, teamMember)
+	}
+
+	return merged
+}
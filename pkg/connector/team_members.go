@@ -0,0 +1,214 @@
+package connector
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+	"github.com/conductorone/baton-freshbooks/pkg/webhooks"
+	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// DefaultTeamMemberCacheTTL is how long the team members of a business are reused before they are requested again.
+const DefaultTeamMemberCacheTTL = 10 * time.Minute
+
+// teamMemberCache keeps the team members of each business, so the users, the role grants and the project grants
+// of a sync are built from a single pass over the team members API.
+// The team members of a business are requested again once the TTL expires, or after they are invalidated
+// because a provisioning call changed them. With a state, they are also kept between syncs, and only the ones
+// updated since are requested again. With a webhook queue, the businesses FreshBooks sent a notification for
+// are requested again before their TTL expires.
+type teamMemberCache struct {
+	client *client.FreshBooksClient
+	ttl    time.Duration
+	state  *teamMemberState
+	queue  *webhooks.Queue
+	now    func() time.Time
+
+	mu       sync.Mutex
+	entries  map[string]*teamMemberEntry
+	notified map[string]bool
+}
+
+// teamMemberEntry holds the team members of a business. Its mutex is held while they are requested,
+// so the builders asking for the same business at the same time wait for a single request.
+type teamMemberEntry struct {
+	mu        sync.Mutex
+	snapshot  teamMemberSnapshot
+	fetchedAt time.Time
+	valid     bool
+}
+
+// newTeamMemberCache returns a cache of the team members, which are kept between syncs in state when it isn't nil,
+// and requested again on the notifications of queue when it isn't nil.
+func newTeamMemberCache(c *client.FreshBooksClient, ttl time.Duration, state *teamMemberState, queue *webhooks.Queue) *teamMemberCache {
+	return &teamMemberCache{
+		client:   c,
+		ttl:      ttl,
+		state:    state,
+		queue:    queue,
+		now:      time.Now,
+		entries:  make(map[string]*teamMemberEntry),
+		notified: make(map[string]bool),
+	}
+}
+
+// Get returns every team member of a business, requesting them when they aren't kept or have expired.
+// The annotations carry the rate limit state of the last page requested, if any was.
+func (c *teamMemberCache) Get(ctx context.Context, businessID string) ([]client.TeamMember, annotations.Annotations, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[businessID]
+	if !ok {
+		entry = &teamMemberEntry{}
+		c.entries[businessID] = entry
+	}
+	c.mu.Unlock()
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	notified := c.takeNotification(ctx, businessID)
+	if entry.valid {
+		if c.now().Sub(entry.fetchedAt) < c.ttl && !notified {
+			return entry.snapshot.TeamMembers, nil, nil
+		}
+
+		// The responses of the previous requests are still in the HTTP cache, which has a TTL of its own.
+		err := c.client.ClearHTTPCache(ctx)
+		if err != nil {
+			ctxzap.Extract(ctx).Warn("error clearing the http cache", zap.Error(err))
+		}
+	}
+
+	snapshot, annotation, err := c.fetch(ctx, businessID, entry.snapshot)
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	entry.snapshot = snapshot
+	entry.fetchedAt = c.now()
+	entry.valid = true
+
+	return snapshot.TeamMembers, annotation, nil
+}
+
+// fetch requests the team members of a business. With a state, only the team members updated since the high-water
+// mark of the previous snapshot, from this sync or the previous one, are requested and merged into it, until every
+// team member is due to be requested again. When FreshBooks ignores the filter and answers every team member, they
+// replace the snapshot instead.
+func (c *teamMemberCache) fetch(ctx context.Context, businessID string, previous teamMemberSnapshot) (teamMemberSnapshot, annotations.Annotations, error) {
+	if c.state == nil {
+		teamMembers, annotation, err := c.client.ListAllTeamMembers(ctx, businessID)
+		return teamMemberSnapshot{TeamMembers: teamMembers}, annotation, err
+	}
+
+	if previous.FullFetchedAt.IsZero() {
+		previous, _ = c.state.Load(businessID)
+	}
+
+	var snapshot teamMemberSnapshot
+	var annotation annotations.Annotations
+	now := c.now()
+	if previous.HighWaterMark.IsZero() || now.Sub(previous.FullFetchedAt) >= teamMemberFullRefreshInterval {
+		teamMembers, annos, err := c.client.ListAllTeamMembers(ctx, businessID)
+		if err != nil {
+			return teamMemberSnapshot{}, annos, err
+		}
+		snapshot = teamMemberSnapshot{TeamMembers: teamMembers, FullFetchedAt: now}
+		annotation = annos
+	} else {
+		changed, annos, err := c.client.ListAllTeamMembers(ctx, businessID, client.WithUpdatedSince(previous.HighWaterMark))
+		if err != nil {
+			return teamMemberSnapshot{}, annos, err
+		}
+		annotation = annos
+
+		if isFullTeamMemberList(changed, previous) {
+			// FreshBooks answered every team member, which is as good as requesting all of them.
+			ctxzap.Extract(ctx).Debug("the team members updated since the previous request are all of them, keeping them as a full list",
+				zap.String("business_id", businessID),
+				zap.Int("team_members", len(changed)),
+			)
+			snapshot = teamMemberSnapshot{TeamMembers: changed, FullFetchedAt: now}
+		} else {
+			snapshot = teamMemberSnapshot{TeamMembers: mergeTeamMembers(previous.TeamMembers, changed), FullFetchedAt: previous.FullFetchedAt}
+		}
+	}
+	snapshot.HighWaterMark = teamMembersHighWaterMark(snapshot.TeamMembers)
+
+	// The team members are still good for this sync when they can't be kept for the next one.
+	err := c.state.Save(businessID, snapshot)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn("error saving the team member state", zap.String("business_id", businessID), zap.Error(err))
+	}
+
+	return snapshot, annotation, nil
+}
+
+// takeNotification reports whether FreshBooks sent a notification for a business since its team members were last
+// requested, draining the webhook queue first. The notifications only cause extra requests: a team member change
+// has no webhook, so the team members are still requested once their TTL expires.
+func (c *teamMemberCache) takeNotification(ctx context.Context, businessID string) bool {
+	if c.queue == nil {
+		return false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	notifications, err := c.queue.Drain(ctx)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn("error reading the webhook queue", zap.Error(err))
+	}
+
+	for _, notification := range notifications {
+		if notification.BusinessID != "" {
+			c.notified[notification.BusinessID] = true
+			continue
+		}
+
+		// A notification that only carries the accounting account is matched with its business.
+		for _, business := range c.client.Businesses() {
+			if notification.AccountID != "" && business.AccountID == notification.AccountID {
+				c.notified[strconv.FormatInt(business.ID, 10)] = true
+			}
+		}
+	}
+
+	notified := c.notified[businessID]
+	delete(c.notified, businessID)
+
+	return notified
+}
+
+// Refresh requests the team members of a business again, past the HTTP cache, and keeps them for the builders.
+func (c *teamMemberCache) Refresh(ctx context.Context, businessID string) ([]client.TeamMember, annotations.Annotations, error) {
+	err := c.client.ClearHTTPCache(ctx)
+	if err != nil {
+		ctxzap.Extract(ctx).Warn("error clearing the http cache", zap.Error(err))
+	}
+
+	c.Invalidate(businessID)
+
+	return c.Get(ctx, businessID)
+}
+
+// Invalidate marks the team members of a business as expired, so they are requested again after a change.
+// They are still used as the base of the next incremental request.
+func (c *teamMemberCache) Invalidate(businessID string) {
+	c.mu.Lock()
+	entry, ok := c.entries[businessID]
+	c.mu.Unlock()
+	if !ok {
+		return
+	}
+
+	entry.mu.Lock()
+	defer entry.mu.Unlock()
+
+	entry.valid = false
+}
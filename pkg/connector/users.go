@@ -2,64 +2,126 @@ package connector
 
 import (
 	"context"
+	"strconv"
+	"strings"
 
 	"github.com/conductorone/baton-freshbooks/pkg/client"
 	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
 	"github.com/conductorone/baton-sdk/pkg/annotations"
+	"github.com/conductorone/baton-sdk/pkg/connectorbuilder"
 	"github.com/conductorone/baton-sdk/pkg/pagination"
 	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	userStatusActive   = "active"
+	userStatusInactive = "inactive"
+	userStatusPending  = "pending"
 )
 
 type userBuilder struct {
 	resourceType *v2.ResourceType
 	client       *client.FreshBooksClient
+	teamMembers  *teamMemberCache
+	defaultRole  string
+	classifier   accountClassifier
 }
 
 func (u *userBuilder) ResourceType(_ context.Context) *v2.ResourceType {
 	return userResourceType
 }
 
-// List returns all the users from the database as resource objects.
+// List returns all the users of a business as resource objects.
 // Users include a UserTrait because they are the 'shape' of a standard user.
+// The team members come first, from the cache shared with the roles and projects, and are paged through in memory,
+// the page token being the offset of the next page. The legacy staff of the accounting account follow them.
 func (u *userBuilder) List(ctx context.Context, parentResourceID *v2.ResourceId, pToken *pagination.Token) ([]*v2.Resource, string, annotations.Annotations, error) {
-	var rv []*v2.Resource
-	err := u.client.EnsureBusinessID(ctx)
-	if err != nil {
-		return nil, "", nil, err
+	if parentResourceID == nil {
+		return nil, "", nil, nil
 	}
 
-	bag, pageToken, err := getToken(pToken, userResourceType)
+	bag := &pagination.Bag{}
+	err := bag.Unmarshal(pToken.Token)
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	teamMembers, nextPageToken, annotation, err := u.client.ListTeamMembers(ctx, client.PageOptions{
-		Page:    pageToken,
-		PerPage: pToken.Size,
-	})
+	if bag.Current() == nil {
+		bag.Push(pagination.PageState{ResourceTypeID: staffPageStateID})
+		bag.Push(pagination.PageState{ResourceTypeID: userResourceType.Id})
+	}
+
+	var (
+		rv         []*v2.Resource
+		annotation annotations.Annotations
+	)
+	if bag.ResourceTypeID() == staffPageStateID {
+		rv, annotation, err = u.listStaffPage(ctx, parentResourceID, bag, pToken.Size)
+	} else {
+		rv, annotation, err = u.listTeamMembersPage(ctx, parentResourceID, bag, pToken.Size)
+	}
 	if err != nil {
-		return nil, "", nil, err
+		return nil, "", annotation, err
 	}
 
-	err = bag.Next(nextPageToken)
+	nextPageToken, err := bag.Marshal()
 	if err != nil {
 		return nil, "", nil, err
 	}
 
-	for _, teamMember := range teamMembers {
-		userResource, err := parseIntoUserResource(teamMember, parentResourceID)
+	return rv, nextPageToken, annotation, nil
+}
+
+// listTeamMembersPage returns the users of a page of the team members, and moves the bag to the next page.
+func (u *userBuilder) listTeamMembersPage(
+	ctx context.Context,
+	parentResourceID *v2.ResourceId,
+	bag *pagination.Bag,
+	pageSize int,
+) ([]*v2.Resource, annotations.Annotations, error) {
+	offset := 0
+	if bag.PageToken() != "" {
+		var err error
+		offset, err = strconv.Atoi(bag.PageToken())
 		if err != nil {
-			return nil, "", nil, err
+			return nil, nil, err
 		}
-		rv = append(rv, userResource)
 	}
 
-	nextPageToken, err = bag.Marshal()
+	teamMembers, annotation, err := u.teamMembers.Get(ctx, parentResourceID.Resource)
 	if err != nil {
-		return nil, "", nil, err
+		return nil, annotation, err
 	}
 
-	return rv, nextPageToken, annotation, nil
+	if pageSize <= 0 {
+		pageSize = client.ItemsPerPage
+	}
+
+	end := min(offset+pageSize, len(teamMembers))
+	offset = min(offset, end)
+
+	nextPageToken := ""
+	if end < len(teamMembers) {
+		nextPageToken = strconv.Itoa(end)
+	}
+
+	err = bag.Next(nextPageToken)
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	var rv []*v2.Resource
+	for _, teamMember := range teamMembers[offset:end] {
+		userResource, err := parseIntoUserResource(teamMember, parentResourceID, u.classifier)
+		if err != nil {
+			return nil, annotation, err
+		}
+		rv = append(rv, userResource)
+	}
+
+	return rv, annotation, nil
 }
 
 // Entitlements always returns an empty slice for users.
@@ -72,16 +134,224 @@ func (u *userBuilder) Grants(_ context.Context, _ *v2.Resource, _ *pagination.To
 	return nil, "", nil, nil
 }
 
-func newUserBuilder(client *client.FreshBooksClient) *userBuilder {
+// CreateAccount invites a person to join a business as a team member. FreshBooks emails the invitation, and the
+// password is set when it is accepted, so the user is returned pending and no credentials are generated.
+func (u *userBuilder) CreateAccount(
+	ctx context.Context,
+	accountInfo *v2.AccountInfo,
+	_ *v2.CredentialOptions,
+) (connectorbuilder.CreateAccountResponse, []*v2.PlaintextData, annotations.Annotations, error) {
+	userResource, annotation, err := u.invite(ctx, accountInfo, "")
+	if err != nil {
+		return nil, nil, annotation, err
+	}
+
+	return &v2.CreateAccountResponse_SuccessResult{
+		Resource:              userResource,
+		IsCreateAccountResult: true,
+	}, nil, annotation, nil
+}
+
+// CreateAccountCapabilityDetails advertises that no password is set, since the users set it through the invitation.
+func (u *userBuilder) CreateAccountCapabilityDetails(_ context.Context) (*v2.CredentialDetailsAccountProvisioning, annotations.Annotations, error) {
+	return &v2.CredentialDetailsAccountProvisioning{
+		SupportedCredentialOptions: []v2.CapabilityDetailCredentialOption{
+			v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+		},
+		PreferredCredentialOption: v2.CapabilityDetailCredentialOption_CAPABILITY_DETAIL_CREDENTIAL_OPTION_NO_PASSWORD,
+	}, nil, nil
+}
+
+// Create invites the person described by the user trait of the resource, like CreateAccount does, into the business
+// the resource belongs to unless its profile names another one.
+func (u *userBuilder) Create(ctx context.Context, resource *v2.Resource) (*v2.Resource, annotations.Annotations, error) {
+	if resource.GetId().GetResourceType() != userResourceType.Id {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "baton-freshbooks: only users can be created, got %s", resource.GetId().GetResourceType())
+	}
+
+	userTrait, err := rs.GetUserTrait(resource)
+	if err != nil {
+		return nil, nil, status.Errorf(codes.InvalidArgument, "baton-freshbooks: a user is created from its user trait: %s", err)
+	}
+
+	accountInfo := &v2.AccountInfo{Profile: userTrait.GetProfile()}
+	for _, email := range userTrait.GetEmails() {
+		accountInfo.Emails = append(accountInfo.Emails, &v2.AccountInfo_Email{
+			Address:   email.GetAddress(),
+			IsPrimary: email.GetIsPrimary(),
+		})
+	}
+
+	parentBusinessID := ""
+	if resource.GetParentResourceId().GetResourceType() == businessResourceType.Id {
+		parentBusinessID = resource.GetParentResourceId().GetResource()
+	}
+
+	return u.invite(ctx, accountInfo, parentBusinessID)
+}
+
+// invite invites the person of the account info as a team member and returns its User Resource.
+func (u *userBuilder) invite(ctx context.Context, accountInfo *v2.AccountInfo, parentBusinessID string) (*v2.Resource, annotations.Annotations, error) {
+	invitation, businessID, err := u.parseAccountInfo(ctx, accountInfo, parentBusinessID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	teamMember, annotation, err := u.client.InviteTeamMember(ctx, businessID, invitation)
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	u.teamMembers.Invalidate(businessID)
+
+	parentResourceID, err := rs.NewResourceID(businessResourceType, businessID)
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	userResource, err := parseIntoUserResource(*teamMember, parentResourceID, u.classifier)
+	if err != nil {
+		return nil, annotation, err
+	}
+
+	return userResource, annotation, nil
+}
+
+// Delete deactivates the team member, which loses the access to the business. The owner of a business can't be
+// deactivated, and deactivating a team member that is already inactive does nothing.
+func (u *userBuilder) Delete(ctx context.Context, resourceId *v2.ResourceId) (annotations.Annotations, error) {
+	if resourceId.ResourceType != userResourceType.Id {
+		return nil, status.Errorf(codes.InvalidArgument, "baton-freshbooks: only users can be deleted, got %s", resourceId.ResourceType)
+	}
+
+	if isStaffUserResourceID(resourceId.Resource) {
+		return nil, status.Errorf(codes.FailedPrecondition, "baton-freshbooks: %s is a legacy staff of the accounting account, only team members can be deactivated", resourceId.Resource)
+	}
+
+	teamMember, businessID, annotation, err := u.lookupTeamMember(ctx, resourceId.Resource)
+	if err != nil {
+		return annotation, err
+	}
+
+	if teamMember.BusinessRoleName == ownerRoleName {
+		return annotation, status.Errorf(codes.FailedPrecondition, "baton-freshbooks: %s is the owner of the business and can't be deactivated", resourceId.Resource)
+	}
+
+	if !teamMember.Active {
+		return annotation, nil
+	}
+
+	active := false
+	_, annotation, err = u.client.UpdateTeamMember(ctx, businessID, teamMember.UUID, client.TeamMemberUpdate{
+		Active: &active,
+	})
+	if err != nil {
+		return annotation, err
+	}
+
+	u.teamMembers.Invalidate(businessID)
+
+	return annotation, nil
+}
+
+// lookupTeamMember finds the team member with the given UUID in the businesses of the identity, returning it along with
+// the ID of its business, since the ID of a User Resource doesn't carry it.
+func (u *userBuilder) lookupTeamMember(ctx context.Context, teamMemberUUID string) (*client.TeamMember, string, annotations.Annotations, error) {
+	err := u.client.EnsureBusinesses(ctx)
+	if err != nil {
+		return nil, "", nil, err
+	}
+
+	var annotation annotations.Annotations
+	for _, business := range u.client.Businesses() {
+		businessID := strconv.FormatInt(business.ID, 10)
+
+		var teamMember *client.TeamMember
+		teamMember, annotation, err = u.client.GetTeamMember(ctx, businessID, teamMemberUUID)
+		switch {
+		case status.Code(err) == codes.NotFound:
+			continue
+		case err != nil:
+			return nil, "", annotation, err
+		}
+
+		return teamMember, businessID, annotation, nil
+	}
+
+	return nil, "", annotation, status.Errorf(codes.NotFound, "baton-freshbooks: team member %s not found in any business", teamMemberUUID)
+}
+
+// parseAccountInfo builds the invitation from the account info, along with the business the person is invited to.
+// The business defaults to parentBusinessID, and can be left out when the identity is a member of a single one.
+// The role defaults to the default role.
+func (u *userBuilder) parseAccountInfo(ctx context.Context, accountInfo *v2.AccountInfo, parentBusinessID string) (client.TeamMemberInvitation, string, error) {
+	profile := accountInfo.GetProfile()
+
+	email, ok := rs.GetProfileStringValue(profile, "email")
+	if !ok || email == "" {
+		for _, accountEmail := range accountInfo.GetEmails() {
+			if email == "" || accountEmail.GetIsPrimary() {
+				email = accountEmail.GetAddress()
+			}
+		}
+	}
+	if email == "" {
+		return client.TeamMemberInvitation{}, "", status.Error(codes.InvalidArgument, "baton-freshbooks: email is required to invite a team member")
+	}
+
+	role, ok := rs.GetProfileStringValue(profile, "role")
+	if !ok || role == "" {
+		role = u.defaultRole
+	}
+	if role == ownerRoleName || !isAvailableRole(role) {
+		return client.TeamMemberInvitation{}, "", status.Errorf(codes.InvalidArgument, "baton-freshbooks: %q is not a role a team member can be invited with", role)
+	}
+
+	businessID, ok := rs.GetProfileStringValue(profile, "business_id")
+	if !ok || businessID == "" {
+		businessID = parentBusinessID
+	}
+	if businessID == "" {
+		err := u.client.EnsureBusinesses(ctx)
+		if err != nil {
+			return client.TeamMemberInvitation{}, "", err
+		}
+
+		businesses := u.client.Businesses()
+		if len(businesses) != 1 {
+			return client.TeamMemberInvitation{}, "", status.Error(codes.InvalidArgument, "baton-freshbooks: business_id is required, the identity is a member of several businesses")
+		}
+		businessID = strconv.FormatInt(businesses[0].ID, 10)
+	}
+
+	firstName, _ := rs.GetProfileStringValue(profile, "first_name")
+	lastName, _ := rs.GetProfileStringValue(profile, "last_name")
+
+	return client.TeamMemberInvitation{
+		Email:            email,
+		FirstName:        firstName,
+		LastName:         lastName,
+		BusinessRoleName: role,
+	}, businessID, nil
+}
+
+func newUserBuilder(client *client.FreshBooksClient, teamMembers *teamMemberCache, defaultRole string, classifier accountClassifier) *userBuilder {
 	return &userBuilder{
 		resourceType: userResourceType,
 		client:       client,
+		teamMembers:  teamMembers,
+		defaultRole:  defaultRole,
+		classifier:   classifier,
 	}
 }
 
 // parseIntoUserResource parses a TeamMember (users from FreshBooks) into a User Resource.
-func parseIntoUserResource(teamMember client.TeamMember, parentResourceID *v2.ResourceId) (*v2.Resource, error) {
-	var userStatus = v2.UserTrait_Status_STATUS_ENABLED
+// Deactivated team members and the ones that haven't accepted their invitation yet are disabled,
+// the status in the profile tells them apart.
+// Every team member is a person, so the account type is human. The SDK has no account type for the people outside
+// of the business, so the external ones are flagged in the profile.
+func parseIntoUserResource(teamMember client.TeamMember, parentResourceID *v2.ResourceId, classifier accountClassifier) (*v2.Resource, error) {
+	userStatus, statusDetails := teamMemberStatus(teamMember)
 
 	profile := map[string]interface{}{
 		"uuid":                teamMember.UUID,
@@ -89,17 +359,30 @@ func parseIntoUserResource(teamMember client.TeamMember, parentResourceID *v2.Re
 		"first_name":          teamMember.FirstName,
 		"last_name":           teamMember.LastName,
 		"active":              teamMember.Active,
+		"status":              statusDetails,
 		"invitation_accepted": teamMember.InvitationDateAccepted,
+		"updated_at":          teamMember.UpdatedAt,
+		"source":              userSourceTeamMember,
+		"external":            classifier.isExternal(teamMember.Email, teamMember.BusinessRoleName),
 	}
 
 	userTraits := []rs.UserTraitOption{
 		rs.WithUserProfile(profile),
-		rs.WithStatus(userStatus),
+		rs.WithDetailedStatus(userStatus, statusDetails),
+		rs.WithAccountType(v2.UserTrait_ACCOUNT_TYPE_HUMAN),
 		rs.WithUserLogin(teamMember.Email),
 		rs.WithEmail(teamMember.Email, true),
 	}
 
-	displayName := teamMember.FirstName + " " + teamMember.LastName
+	if createdAt, ok := parseTimestamp(teamMember.CreatedAt); ok {
+		userTraits = append(userTraits, rs.WithCreatedAt(createdAt))
+	}
+
+	if acceptedAt, ok := parseTimestamp(teamMember.InvitationDateAccepted); ok {
+		userTraits = append(userTraits, rs.WithLastLogin(acceptedAt))
+	}
+
+	displayName := strings.TrimSpace(teamMember.FirstName + " " + teamMember.LastName)
 	if displayName == "" {
 		displayName = teamMember.Email
 	}
@@ -117,3 +400,16 @@ func parseIntoUserResource(teamMember client.TeamMember, parentResourceID *v2.Re
 
 	return ret, nil
 }
+
+// teamMemberStatus maps the state of a team member to the status of its User Resource, along with the details
+// of the status: active, inactive or pending, when the invitation hasn't been accepted yet.
+func teamMemberStatus(teamMember client.TeamMember) (v2.UserTrait_Status_Status, string) {
+	switch {
+	case !teamMember.Active:
+		return v2.UserTrait_Status_STATUS_DISABLED, userStatusInactive
+	case teamMember.Invited && teamMember.InvitationDateAccepted == "":
+		return v2.UserTrait_Status_STATUS_DISABLED, userStatusPending
+	default:
+		return v2.UserTrait_Status_STATUS_ENABLED, userStatusActive
+	}
+}
@@ -0,0 +1,163 @@
+package connector
+
+import (
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+	v2 "github.com/conductorone/baton-sdk/pb/c1/connector/v2"
+	rs "github.com/conductorone/baton-sdk/pkg/types/resource"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseIntoUserResourceStatus(t *testing.T) {
+	tests := []struct {
+		name           string
+		teamMember     client.TeamMember
+		expectedStatus v2.UserTrait_Status_Status
+		expectedDetail string
+	}{
+		{
+			name:           "active",
+			teamMember:     client.TeamMember{Active: true, Invited: true, InvitationDateAccepted: "2024-03-01T10:00:00Z"},
+			expectedStatus: v2.UserTrait_Status_STATUS_ENABLED,
+			expectedDetail: userStatusActive,
+		},
+		{
+			name:           "inactive",
+			teamMember:     client.TeamMember{Active: false, Invited: true, InvitationDateAccepted: "2024-03-01T10:00:00Z"},
+			expectedStatus: v2.UserTrait_Status_STATUS_DISABLED,
+			expectedDetail: userStatusInactive,
+		},
+		{
+			name:           "pending invitation",
+			teamMember:     client.TeamMember{Active: true, Invited: true},
+			expectedStatus: v2.UserTrait_Status_STATUS_DISABLED,
+			expectedDetail: userStatusPending,
+		},
+	}
+
+	parentResourceID := &v2.ResourceId{ResourceType: businessResourceType.Id, Resource: "1"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.teamMember.UUID = "abc"
+			tt.teamMember.Email = "jane@example.com"
+
+			userResource, err := parseIntoUserResource(tt.teamMember, parentResourceID, accountClassifier{})
+			require.NoError(t, err)
+
+			userTrait, err := rs.GetUserTrait(userResource)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, userTrait.GetStatus().GetStatus())
+			assert.Equal(t, tt.expectedDetail, userTrait.GetStatus().GetDetails())
+
+			profileStatus, ok := rs.GetProfileStringValue(userTrait.GetProfile(), "status")
+			assert.True(t, ok)
+			assert.Equal(t, tt.expectedDetail, profileStatus)
+		})
+	}
+}
+
+func TestParseIntoUserResourceTimestamps(t *testing.T) {
+	teamMember := client.TeamMember{
+		UUID:                   "abc",
+		Email:                  "jane@example.com",
+		Active:                 true,
+		CreatedAt:              "2023-11-20 08:30:00",
+		InvitationDateAccepted: "2023-11-21T09:15:00Z",
+		UpdatedAt:              "2024-01-02T00:00:00Z",
+	}
+
+	userResource, err := parseIntoUserResource(teamMember, &v2.ResourceId{ResourceType: businessResourceType.Id, Resource: "1"}, accountClassifier{})
+	require.NoError(t, err)
+	assert.Equal(t, "jane@example.com", userResource.DisplayName)
+
+	userTrait, err := rs.GetUserTrait(userResource)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2023, 11, 20, 8, 30, 0, 0, time.UTC), userTrait.GetCreatedAt().AsTime())
+	assert.Equal(t, time.Date(2023, 11, 21, 9, 15, 0, 0, time.UTC), userTrait.GetLastLogin().AsTime())
+
+	updatedAt, ok := rs.GetProfileStringValue(userTrait.GetProfile(), "updated_at")
+	assert.True(t, ok)
+	assert.Equal(t, teamMember.UpdatedAt, updatedAt)
+}
+
+func TestParseIntoUserResourceClassification(t *testing.T) {
+	tests := []struct {
+		name             string
+		corporateDomains []string
+		teamMember       client.TeamMember
+		expectedExternal bool
+	}{
+		{
+			name:             "employee without corporate domains",
+			teamMember:       client.TeamMember{Email: "jane@bookkeepers.com", BusinessRoleName: "business_employee"},
+			expectedExternal: false,
+		},
+		{
+			name:             "accountant without corporate domains",
+			teamMember:       client.TeamMember{Email: "jane@example.com", BusinessRoleName: "no_seat_employee"},
+			expectedExternal: true,
+		},
+		{
+			name:             "corporate domain",
+			corporateDomains: []string{"@Example.com"},
+			teamMember:       client.TeamMember{Email: "jane@example.com", BusinessRoleName: "no_seat_employee"},
+			expectedExternal: false,
+		},
+		{
+			name:             "corporate subdomain",
+			corporateDomains: []string{"example.org, example.com"},
+			teamMember:       client.TeamMember{Email: "jane@eu.example.com", BusinessRoleName: "business_manager"},
+			expectedExternal: false,
+		},
+		{
+			name:             "other domain",
+			corporateDomains: []string{"example.com"},
+			teamMember:       client.TeamMember{Email: "jane@notexample.com", BusinessRoleName: "business_manager"},
+			expectedExternal: true,
+		},
+	}
+
+	parentResourceID := &v2.ResourceId{ResourceType: businessResourceType.Id, Resource: "1"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.teamMember.UUID = "abc"
+
+			userResource, err := parseIntoUserResource(tt.teamMember, parentResourceID, newAccountClassifier(tt.corporateDomains))
+			require.NoError(t, err)
+
+			userTrait, err := rs.GetUserTrait(userResource)
+			require.NoError(t, err)
+			assert.Equal(t, v2.UserTrait_ACCOUNT_TYPE_HUMAN, userTrait.GetAccountType())
+
+			external, ok := userTrait.GetProfile().GetFields()["external"]
+			require.True(t, ok)
+			assert.Equal(t, tt.expectedExternal, external.GetBoolValue())
+		})
+	}
+}
+
+func TestParseIntoClientContactResourcesAreExternal(t *testing.T) {
+	fbClient := client.Client{
+		ID:       12,
+		Email:    "jane@example.com",
+		Contacts: []client.ClientContact{{ID: 3, Email: "john@example.com"}},
+	}
+	parentResourceID := &v2.ResourceId{ResourceType: businessResourceType.Id, Resource: "1"}
+
+	contactResources, err := parseIntoClientContactResources(fbClient, parentResourceID)
+	require.NoError(t, err)
+	require.Len(t, contactResources, 2)
+
+	for _, contactResource := range contactResources {
+		userTrait, err := rs.GetUserTrait(contactResource)
+		require.NoError(t, err)
+		assert.Equal(t, v2.UserTrait_ACCOUNT_TYPE_HUMAN, userTrait.GetAccountType())
+
+		external, ok := userTrait.GetProfile().GetFields()["external"]
+		require.True(t, ok)
+		assert.True(t, external.GetBoolValue())
+	}
+}
@@ -0,0 +1,105 @@
+package connector
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+	"github.com/conductorone/baton-freshbooks/pkg/webhooks"
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// WithWebhookQueue reads the notifications queued by the webhook server, so the team members of the businesses
+// FreshBooks reported a change for are requested again before their cache expires.
+func WithWebhookQueue(queue *webhooks.Queue) Option {
+	return func(c *Connector) error {
+		c.webhookQueue = queue
+		return nil
+	}
+}
+
+// RegisterWebhooks registers a callback to uri for each of the events on the accounting account of every business,
+// unless it is already registered, and keeps them in callbacks so the webhook server accepts their verification.
+// FreshBooks posts the verification of a new callback as soon as it is created, possibly before it is kept, so
+// the verification of every callback that isn't verified yet is asked again once it is.
+// The businesses without an accounting account are skipped, since the callbacks are scoped by it.
+func (d *Connector) RegisterWebhooks(ctx context.Context, uri string, events []string, callbacks *webhooks.VerifierStore) error {
+	l := ctxzap.Extract(ctx)
+
+	err := d.client.EnsureBusinesses(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, business := range d.client.Businesses() {
+		businessID := strconv.FormatInt(business.ID, 10)
+
+		accountID, err := d.client.AccountID(ctx, businessID)
+		if errors.Is(err, client.ErrNoAccountingAccount) {
+			l.Warn("business has no accounting account, skipping its webhooks", zap.String("business_id", businessID))
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		registered, _, err := d.client.ListCallbacks(ctx, accountID)
+		if err != nil {
+			return fmt.Errorf("error listing the webhook callbacks of the business %q (%d): %w", business.Name, business.ID, err)
+		}
+
+		for _, event := range events {
+			callback, ok := findCallback(registered, event, uri)
+			if !ok {
+				created, _, err := d.client.CreateCallback(ctx, accountID, event, uri)
+				if err != nil {
+					return fmt.Errorf("error registering the %s webhook of the business %q (%d): %w", event, business.Name, business.ID, err)
+				}
+				callback = *created
+
+				l.Info(
+					"webhook callback registered",
+					zap.String("business_id", businessID),
+					zap.String("event", event),
+					zap.Int64("callback_id", callback.CallbackID),
+				)
+			}
+
+			callbackID := strconv.FormatInt(callback.CallbackID, 10)
+			err = callbacks.Register(callbackID, accountID)
+			if err != nil {
+				return err
+			}
+			if callback.Verified && callbacks.Verified(callbackID) {
+				continue
+			}
+
+			_, err = d.client.ResendCallbackVerification(ctx, accountID, callbackID)
+			if err != nil {
+				return fmt.Errorf("error resending the verification of the %s webhook of the business %q (%d): %w", event, business.Name, business.ID, err)
+			}
+		}
+	}
+
+	return nil
+}
+
+// VerifyWebhook confirms a callback with the verifier FreshBooks posted to it.
+func (d *Connector) VerifyWebhook(ctx context.Context, accountID, callbackID, verifier string) error {
+	_, _, err := d.client.VerifyCallback(ctx, accountID, callbackID, verifier)
+	return err
+}
+
+// findCallback looks for the callback of an event posted to uri.
+func findCallback(callbacks []client.Callback, event, uri string) (client.Callback, bool) {
+	for _, callback := range callbacks {
+		if callback.Event == event && callback.URI == uri {
+			return callback, true
+		}
+	}
+
+	return client.Callback{}, false
+}
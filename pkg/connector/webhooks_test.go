@@ -0,0 +1,105 @@
+package connector
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+	"github.com/conductorone/baton-freshbooks/pkg/webhooks"
+	"github.com/conductorone/baton-sdk/pkg/pagination"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWebhooksRefreshTeamMembers(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+
+	queue, err := webhooks.NewQueue(filepath.Join(t.TempDir(), "queue.jsonl"))
+	require.NoError(t, err)
+	connector := newFakeConnector(t, server, WithWebhookQueue(queue))
+
+	verifiers, err := webhooks.NewVerifierStore("", "")
+	require.NoError(t, err)
+	receiver := httptest.NewServer(webhooks.NewHandler(verifiers, queue, connector.VerifyWebhook))
+	t.Cleanup(receiver.Close)
+
+	require.NoError(t, connector.RegisterWebhooks(ctx, receiver.URL, []string{"client", "project"}, verifiers))
+	require.Eventually(t, func() bool {
+		callbacks := server.Callbacks("xZNQ1X")
+		return len(callbacks) == 2 && callbacks[0].Verified && callbacks[1].Verified
+	}, 5*time.Second, 10*time.Millisecond)
+
+	require.Eventually(t, func() bool {
+		return len(verifiers.Verifiers()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// The callbacks that are already registered aren't registered again.
+	requests := len(server.Requests())
+	require.NoError(t, connector.RegisterWebhooks(ctx, receiver.URL, []string{"client", "project"}, verifiers))
+	assert.Len(t, server.Callbacks("xZNQ1X"), 2)
+	for _, request := range server.Requests()[requests:] {
+		assert.False(t, strings.HasPrefix(request, http.MethodPut+" "), request)
+	}
+
+	_, _, err = connector.teamMembers.Get(ctx, "4521187")
+	require.NoError(t, err)
+	requests = teamMemberListRequests(server)
+
+	// Without a notification, the team members are kept until their TTL expires.
+	_, _, err = connector.teamMembers.Get(ctx, "4521187")
+	require.NoError(t, err)
+	assert.Equal(t, requests, teamMemberListRequests(server))
+
+	codes, err := server.Notify(fakeBusinessID, "client.update", "12")
+	require.NoError(t, err)
+	assert.Equal(t, []int{http.StatusOK}, codes)
+
+	_, _, err = connector.teamMembers.Get(ctx, "4521187")
+	require.NoError(t, err)
+	assert.Greater(t, teamMemberListRequests(server), requests)
+
+	// The event feed doesn't wait for a notification, since a team member change has none.
+	_, state, _, err := connector.ListEvents(ctx, nil, &pagination.StreamToken{})
+	require.NoError(t, err)
+
+	_, _, err = connector.client.UpdateTeamMember(ctx, "4521187", "contractor", client.TeamMemberUpdate{BusinessRoleName: "business_manager"})
+	require.NoError(t, err)
+
+	events, _, _, err := connector.ListEvents(ctx, nil, &pagination.StreamToken{Cursor: state.Cursor})
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{
+		"revoke contractor 4521187:contractor",
+		"grant contractor 4521187:business_manager",
+	}, describeEvents(events))
+}
+
+func TestRegisterWebhooksSeesEveryPageOfCallbacks(t *testing.T) {
+	ctx := context.Background()
+	server := newFakeServer(t)
+	c := newFakeClient(t, server)
+
+	// More callbacks than a page holds, the one of the connector on the last page.
+	receiver := httptest.NewServer(http.NotFoundHandler())
+	t.Cleanup(receiver.Close)
+	for i := 0; i < client.ItemsPerPage; i++ {
+		_, _, err := c.CreateCallback(ctx, "xZNQ1X", "invoice", receiver.URL+"/other")
+		require.NoError(t, err)
+	}
+	_, _, err := c.CreateCallback(ctx, "xZNQ1X", "client", receiver.URL)
+	require.NoError(t, err)
+
+	callbacks, _, err := c.ListCallbacks(ctx, "xZNQ1X")
+	require.NoError(t, err)
+	assert.Len(t, callbacks, client.ItemsPerPage+1)
+
+	verifiers, err := webhooks.NewVerifierStore("", "")
+	require.NoError(t, err)
+	require.NoError(t, newFakeConnector(t, server).RegisterWebhooks(ctx, receiver.URL, []string{"client"}, verifiers))
+	assert.Len(t, server.Callbacks("xZNQ1X"), client.ItemsPerPage+1)
+}
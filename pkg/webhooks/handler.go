@@ -0,0 +1,132 @@
+package webhooks
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// maxBodySize limits the size of a notification, FreshBooks only posts a handful of form fields.
+const maxBodySize = 64 << 10
+
+// VerifyFunc confirms a callback with the verifier FreshBooks posted to it.
+type VerifyFunc func(ctx context.Context, accountID, callbackID, verifier string) error
+
+// Handler answers the callbacks FreshBooks posts: it confirms the callbacks registered in its store with their
+// verifier, and queues the notifications signed with the verifier of one of them.
+type Handler struct {
+	verifiers *VerifierStore
+	queue     *Queue
+	verify    VerifyFunc
+	now       func() time.Time
+}
+
+// NewHandler returns a handler that only verifies the callbacks registered in verifiers, keeps their verifiers there,
+// and the notifications in queue.
+func NewHandler(verifiers *VerifierStore, queue *Queue, verify VerifyFunc) *Handler {
+	return &Handler{
+		verifiers: verifiers,
+		queue:     queue,
+		verify:    verify,
+		now:       time.Now,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	l := ctxzap.Extract(r.Context())
+
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxBodySize))
+	if err != nil {
+		http.Error(w, "invalid body", http.StatusBadRequest)
+		return
+	}
+
+	form, err := url.ParseQuery(string(body))
+	if err != nil {
+		http.Error(w, "invalid form", http.StatusBadRequest)
+		return
+	}
+
+	// The verification isn't signed, since the verifier is what it brings. Only the callbacks the server registered
+	// are verified, on their own account, so a forged verification can't make the connector call FreshBooks.
+	// The verifier is then only kept once FreshBooks accepts it, so it can't be used to sign notifications either.
+	if form.Get("name") == verifyEventName {
+		callbackID, verifier := form.Get("object_id"), form.Get("verifier")
+		if callbackID == "" || verifier == "" {
+			http.Error(w, "the verification needs an object_id and a verifier", http.StatusBadRequest)
+			return
+		}
+
+		accountID, ok := h.verifiers.AccountID(callbackID)
+		if !ok || accountID != form.Get("account_id") {
+			l.Warn("verification of an unknown webhook callback rejected", zap.String("callback_id", callbackID))
+			http.Error(w, "unknown callback", http.StatusForbidden)
+			return
+		}
+
+		err = h.verify(r.Context(), accountID, callbackID, verifier)
+		if err != nil {
+			l.Error("error verifying the webhook callback", zap.String("callback_id", callbackID), zap.Error(err))
+			http.Error(w, "the callback could not be verified", http.StatusBadGateway)
+			return
+		}
+
+		err = h.verifiers.Save(callbackID, verifier)
+		if err != nil {
+			l.Error("error saving the webhook verifier", zap.String("callback_id", callbackID), zap.Error(err))
+			http.Error(w, "the verifier could not be saved", http.StatusInternalServerError)
+			return
+		}
+
+		l.Info("webhook callback verified", zap.String("callback_id", callbackID))
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	if !h.signed(body, r.Header.Get(SignatureHeader)) {
+		http.Error(w, "invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	notification := parseNotification(form, h.now())
+	err = h.queue.Append(notification)
+	if err != nil {
+		l.Error("error queueing the webhook notification", zap.String("name", notification.Name), zap.Error(err))
+		http.Error(w, "the notification could not be queued", http.StatusInternalServerError)
+		return
+	}
+
+	l.Debug(
+		"webhook notification queued",
+		zap.String("name", notification.Name),
+		zap.String("object_id", notification.ObjectID),
+		zap.String("business_id", notification.BusinessID),
+	)
+	w.WriteHeader(http.StatusOK)
+}
+
+// signed reports whether the body was signed with the verifier of one of the callbacks.
+func (h *Handler) signed(body []byte, signature string) bool {
+	if signature == "" {
+		return false
+	}
+
+	for _, verifier := range h.verifiers.Verifiers() {
+		if validSignature(verifier, body, signature) {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,140 @@
+package webhooks
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestHandler returns a handler whose verifications are accepted only with the verifier "accepted".
+func newTestHandler(t *testing.T) (*Handler, *Queue) {
+	verifiers, err := NewVerifierStore("", "")
+	require.NoError(t, err)
+
+	queue, err := NewQueue(filepath.Join(t.TempDir(), "queue.jsonl"))
+	require.NoError(t, err)
+
+	handler := NewHandler(verifiers, queue, func(_ context.Context, _, _, verifier string) error {
+		if verifier != "accepted" {
+			return errors.New("verifier rejected")
+		}
+		return nil
+	})
+
+	return handler, queue
+}
+
+func post(handler http.Handler, form url.Values, signature string) int {
+	body := form.Encode()
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if signature != "" {
+		req.Header.Set(SignatureHeader, signature)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+
+	return rec.Code
+}
+
+func TestHandlerVerifiesCallbacks(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	require.NoError(t, handler.verifiers.Register("7", "xZNQ1X"))
+
+	code := post(handler, url.Values{"name": {verifyEventName}, "object_id": {"7"}, "account_id": {"xZNQ1X"}, "verifier": {"forged"}}, "")
+	assert.Equal(t, http.StatusBadGateway, code)
+	assert.Empty(t, handler.verifiers.Verifiers())
+
+	code = post(handler, url.Values{"name": {verifyEventName}, "object_id": {"7"}, "account_id": {"xZNQ1X"}, "verifier": {"accepted"}}, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"accepted"}, handler.verifiers.Verifiers())
+}
+
+func TestHandlerRejectsUnknownCallbacks(t *testing.T) {
+	verifiers, err := NewVerifierStore("", "")
+	require.NoError(t, err)
+	require.NoError(t, verifiers.Register("7", "xZNQ1X"))
+
+	queue, err := NewQueue(filepath.Join(t.TempDir(), "queue.jsonl"))
+	require.NoError(t, err)
+
+	var calls int
+	handler := NewHandler(verifiers, queue, func(context.Context, string, string, string) error {
+		calls++
+		return nil
+	})
+
+	code := post(handler, url.Values{"name": {verifyEventName}, "object_id": {"8"}, "account_id": {"xZNQ1X"}, "verifier": {"accepted"}}, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	code = post(handler, url.Values{"name": {verifyEventName}, "object_id": {"7"}, "account_id": {"other"}, "verifier": {"accepted"}}, "")
+	assert.Equal(t, http.StatusForbidden, code)
+
+	assert.Zero(t, calls)
+	assert.Empty(t, verifiers.Verifiers())
+}
+
+func TestHandlerQueuesSignedNotifications(t *testing.T) {
+	handler, queue := newTestHandler(t)
+	require.NoError(t, handler.verifiers.Register("7", "xZNQ1X"))
+	require.NoError(t, handler.verifiers.Save("7", "accepted"))
+
+	form := url.Values{"name": {"client.update"}, "object_id": {"12"}, "account_id": {"xZNQ1X"}, "business_id": {"4521187"}}
+
+	assert.Equal(t, http.StatusUnauthorized, post(handler, form, ""))
+	assert.Equal(t, http.StatusUnauthorized, post(handler, form, Sign("forged", []byte(form.Encode()))))
+	assert.Equal(t, http.StatusOK, post(handler, form, Sign("accepted", []byte(form.Encode()))))
+
+	notifications, err := queue.Drain(context.Background())
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "client.update", notifications[0].Name)
+	assert.Equal(t, "4521187", notifications[0].BusinessID)
+
+	notifications, err = queue.Drain(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+}
+
+func TestHandlerRejectsOtherMethods(t *testing.T) {
+	handler, _ := newTestHandler(t)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/webhooks", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, rec.Code)
+}
+
+func TestVerifierStorePersists(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "verifiers.json")
+
+	store, err := NewVerifierStore(path, "passphrase")
+	require.NoError(t, err)
+	require.Error(t, store.Save("7", "accepted"))
+	require.NoError(t, store.Register("7", "xZNQ1X"))
+	require.NoError(t, store.Save("7", "accepted"))
+
+	store, err = NewVerifierStore(path, "passphrase")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"accepted"}, store.Verifiers())
+	accountID, ok := store.AccountID("7")
+	assert.True(t, ok)
+	assert.Equal(t, "xZNQ1X", accountID)
+
+	// The verifiers can sign notifications, they aren't written in clear.
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "accepted")
+
+	_, err = NewVerifierStore(path, "wrong passphrase")
+	assert.Error(t, err)
+}
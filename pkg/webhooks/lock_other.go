@@ -0,0 +1,12 @@
+//go:build !unix
+
+package webhooks
+
+import (
+	"os"
+)
+
+// lockFile doesn't lock the file on the platforms without flock, where the queue is only safe inside one process.
+func lockFile(_ *os.File) error {
+	return nil
+}
@@ -0,0 +1,67 @@
+// Package webhooks receives the callbacks FreshBooks posts when something changes in a business.
+//
+// A callback is registered with a URI, and FreshBooks first posts a verification to it, carrying the verifier that
+// confirms the callback. The following notifications are signed with the verifier, and queued once their signature
+// is checked, for the event feed of the connector to refresh the businesses they are about.
+package webhooks
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/url"
+	"time"
+)
+
+const (
+	// SignatureHeader carries the signature of a notification.
+	SignatureHeader = "X-FreshBooks-Hmac-SHA256"
+
+	// verifyEventName is the name of the notification that carries the verifier of a new callback.
+	verifyEventName = "callback.verify"
+)
+
+// Notification is a change FreshBooks reported through a callback.
+type Notification struct {
+	Name       string    `json:"name"`
+	ObjectID   string    `json:"object_id"`
+	AccountID  string    `json:"account_id,omitempty"`
+	BusinessID string    `json:"business_id,omitempty"`
+	IdentityID string    `json:"identity_id,omitempty"`
+	ReceivedAt time.Time `json:"received_at"`
+}
+
+// parseNotification reads a notification from the form FreshBooks posts.
+func parseNotification(form url.Values, receivedAt time.Time) Notification {
+	return Notification{
+		Name:       form.Get("name"),
+		ObjectID:   form.Get("object_id"),
+		AccountID:  form.Get("account_id"),
+		BusinessID: form.Get("business_id"),
+		IdentityID: form.Get("identity_id"),
+		ReceivedAt: receivedAt,
+	}
+}
+
+// Sign returns the signature of a notification body: the base64 encoded HMAC-SHA256 of the body, keyed with the
+// verifier of the callback.
+func Sign(verifier string, body []byte) string {
+	return base64.StdEncoding.EncodeToString(mac(verifier, body))
+}
+
+// validSignature reports whether the signature of a body was made with the verifier.
+func validSignature(verifier string, body []byte, signature string) bool {
+	actual, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+
+	return hmac.Equal(mac(verifier, body), actual)
+}
+
+func mac(verifier string, body []byte) []byte {
+	h := hmac.New(sha256.New, []byte(verifier))
+	h.Write(body)
+
+	return h.Sum(nil)
+}
@@ -0,0 +1,128 @@
+package webhooks
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/grpc-ecosystem/go-grpc-middleware/logging/zap/ctxzap"
+	"go.uber.org/zap"
+)
+
+// Queue keeps the notifications in a file, one JSON object per line, so the webhook server can append them while
+// the connector takes the ones queued so far. The file is locked while it is written or drained, since the server
+// and the connector are separate processes.
+type Queue struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewQueue returns a queue stored in the file at path, which is created on the first notification.
+func NewQueue(path string) (*Queue, error) {
+	if path == "" {
+		return nil, errors.New("the path of the webhook queue is empty")
+	}
+
+	return &Queue{path: path}, nil
+}
+
+// Append adds a notification at the end of the queue.
+func (q *Queue) Append(notification Notification) error {
+	line, err := json.Marshal(notification)
+	if err != nil {
+		return err
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	file, err := os.OpenFile(q.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("error opening the webhook queue: %w", err)
+	}
+	defer file.Close()
+
+	// The lock is released when the file is closed.
+	err = lockFile(file)
+	if err != nil {
+		return fmt.Errorf("error locking the webhook queue: %w", err)
+	}
+
+	_, err = file.Write(append(line, '\n'))
+	if err != nil {
+		return fmt.Errorf("error writing to the webhook queue: %w", err)
+	}
+
+	return nil
+}
+
+// Drain returns the notifications in the queue and removes them from its file, so it doesn't grow past the
+// notifications that weren't taken yet. A line that was left half written is kept for the next drain, and a
+// complete line that isn't a notification is logged and dropped, so it can't hold up the ones after it.
+func (q *Queue) Drain(ctx context.Context) ([]Notification, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	file, err := os.OpenFile(q.path, os.O_RDWR, 0o600)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error opening the webhook queue: %w", err)
+	}
+	defer file.Close()
+
+	err = lockFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("error locking the webhook queue: %w", err)
+	}
+
+	content, err := io.ReadAll(file)
+	if err != nil {
+		return nil, fmt.Errorf("error reading the webhook queue: %w", err)
+	}
+	if len(content) == 0 {
+		return nil, nil
+	}
+
+	var notifications []Notification
+	rest := content
+	for {
+		line, after, ok := bytes.Cut(rest, []byte{'\n'})
+		if !ok {
+			break
+		}
+		rest = after
+
+		line = bytes.TrimSpace(line)
+		if len(line) == 0 {
+			continue
+		}
+
+		var notification Notification
+		err = json.Unmarshal(line, &notification)
+		if err != nil {
+			ctxzap.Extract(ctx).Warn("dropping an invalid notification from the webhook queue", zap.ByteString("line", line), zap.Error(err))
+			continue
+		}
+		notifications = append(notifications, notification)
+	}
+
+	err = file.Truncate(0)
+	if err != nil {
+		return nil, fmt.Errorf("error compacting the webhook queue: %w", err)
+	}
+	if len(rest) > 0 {
+		_, err = file.WriteAt(rest, 0)
+		if err != nil {
+			return nil, fmt.Errorf("error compacting the webhook queue: %w", err)
+		}
+	}
+
+	return notifications, nil
+}
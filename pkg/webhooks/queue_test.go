@@ -0,0 +1,87 @@
+package webhooks
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestQueueDrainsNotifications(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	queue, err := NewQueue(path)
+	require.NoError(t, err)
+
+	notifications, err := queue.Drain(context.Background())
+	require.NoError(t, err)
+	assert.Empty(t, notifications)
+
+	require.NoError(t, queue.Append(Notification{Name: "client.create", ObjectID: "1"}))
+	require.NoError(t, queue.Append(Notification{Name: "client.update", ObjectID: "1"}))
+
+	notifications, err = queue.Drain(context.Background())
+	require.NoError(t, err)
+	require.Len(t, notifications, 2)
+	assert.Equal(t, "client.update", notifications[1].Name)
+
+	// The drained notifications are removed from the file.
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+
+	require.NoError(t, queue.Append(Notification{Name: "project.create", ObjectID: "2"}))
+
+	notifications, err = queue.Drain(context.Background())
+	require.NoError(t, err)
+	require.Len(t, notifications, 1)
+	assert.Equal(t, "project.create", notifications[0].Name)
+}
+
+func TestQueueKeepsPartialLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	queue, err := NewQueue(path)
+	require.NoError(t, err)
+
+	require.NoError(t, queue.Append(Notification{Name: "client.create", ObjectID: "1"}))
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"name":"client.upd`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	notifications, err := queue.Drain(context.Background())
+	require.NoError(t, err)
+	assert.Len(t, notifications, 1)
+
+	content, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.Equal(t, `{"name":"client.upd`, string(content))
+}
+
+func TestQueueDropsInvalidLines(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queue.jsonl")
+	queue, err := NewQueue(path)
+	require.NoError(t, err)
+
+	require.NoError(t, queue.Append(Notification{Name: "client.create", ObjectID: "1"}))
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o600)
+	require.NoError(t, err)
+	_, err = file.WriteString("{\"name\":\"client.upd\x00\n")
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+	require.NoError(t, queue.Append(Notification{Name: "project.create", ObjectID: "2"}))
+
+	notifications, err := queue.Drain(context.Background())
+	require.NoError(t, err)
+	require.Len(t, notifications, 2)
+	assert.Equal(t, "client.create", notifications[0].Name)
+	assert.Equal(t, "project.create", notifications[1].Name)
+
+	// The invalid line is gone with the others, so it doesn't come back on the next drain.
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	assert.Zero(t, info.Size())
+}
@@ -0,0 +1,123 @@
+package webhooks
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/conductorone/baton-freshbooks/pkg/client"
+)
+
+// VerifierStore keeps the callbacks registered by the webhook server, keyed by the callback ID, with the accounting
+// account they belong to and, once verified, their verifier to check the signature of the notifications.
+// When it has a file, the callbacks are kept in it too, so they survive a restart. Anyone with a verifier can sign
+// notifications, so the file is encrypted like the token store.
+type VerifierStore struct {
+	file      *client.SealedFile
+	mu        sync.Mutex
+	callbacks map[string]storedCallback
+}
+
+type storedCallback struct {
+	AccountID string `json:"account_id"`
+	Verifier  string `json:"verifier,omitempty"`
+}
+
+// NewVerifierStore returns a store loaded from the file at path, encrypted with passphrase, or kept in memory only
+// when path is empty.
+func NewVerifierStore(path, passphrase string) (*VerifierStore, error) {
+	store := &VerifierStore{
+		callbacks: make(map[string]storedCallback),
+	}
+	if path == "" {
+		return store, nil
+	}
+
+	file, err := client.NewSealedFile(path, passphrase)
+	if err != nil {
+		return nil, fmt.Errorf("error opening the webhook verifiers: %w", err)
+	}
+	store.file = file
+
+	_, err = file.Load(&store.callbacks)
+	if err != nil {
+		return nil, fmt.Errorf("error loading the webhook verifiers: %w", err)
+	}
+
+	return store, nil
+}
+
+// Register keeps a callback registered on an accounting account, so its verification is accepted.
+// The verifier of a callback that was already kept for the same account is left as it is.
+func (s *VerifierStore) Register(callbackID, accountID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if stored, ok := s.callbacks[callbackID]; ok && stored.AccountID == accountID {
+		return nil
+	}
+	s.callbacks[callbackID] = storedCallback{AccountID: accountID}
+
+	return s.write()
+}
+
+// AccountID returns the accounting account of a registered callback.
+func (s *VerifierStore) AccountID(callbackID string) (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.callbacks[callbackID]
+	return stored.AccountID, ok
+}
+
+// Verified reports whether the verifier of a registered callback is kept.
+func (s *VerifierStore) Verified(callbackID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.callbacks[callbackID].Verifier != ""
+}
+
+// Save keeps the verifier of a registered callback.
+func (s *VerifierStore) Save(callbackID, verifier string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stored, ok := s.callbacks[callbackID]
+	if !ok {
+		return fmt.Errorf("the webhook callback %s isn't registered", callbackID)
+	}
+	stored.Verifier = verifier
+	s.callbacks[callbackID] = stored
+
+	return s.write()
+}
+
+// Verifiers returns the verifiers of every verified callback. The notifications don't say which callback they
+// come from, so a signature made with any of them is accepted.
+func (s *VerifierStore) Verifiers() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	verifiers := make([]string, 0, len(s.callbacks))
+	for _, stored := range s.callbacks {
+		if stored.Verifier != "" {
+			verifiers = append(verifiers, stored.Verifier)
+		}
+	}
+
+	return verifiers
+}
+
+// write saves the callbacks to the file of the store, if it has one. The mutex must be held.
+func (s *VerifierStore) write() error {
+	if s.file == nil {
+		return nil
+	}
+
+	err := s.file.Save(s.callbacks)
+	if err != nil {
+		return fmt.Errorf("error saving the webhook verifiers: %w", err)
+	}
+
+	return nil
+}